@@ -0,0 +1,285 @@
+// Package codegen turns requests into code snippets for other languages and tools.
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	httpclient "percentman/http"
+	"percentman/models"
+)
+
+// generator writes a snippet for one language
+type generator struct {
+	name     string
+	generate func(r *request) string
+}
+
+// generators are listed in the order shown to the user
+var generators = []generator{
+	{"cURL", curl},
+	{"Go (net/http)", goNetHTTP},
+	{"Python (requests)", pythonRequests},
+	{"JavaScript (fetch)", javaScriptFetch},
+	{"Node.js (axios)", nodeAxios},
+	{"Java (HttpClient)", javaHTTPClient},
+	{"HTTPie", httpie},
+	{"PowerShell", powerShell},
+}
+
+// Languages returns the names of the available generators
+func Languages() []string {
+	names := make([]string, len(generators))
+	for i, g := range generators {
+		names[i] = g.name
+	}
+	return names
+}
+
+// Generate returns a snippet that sends req using the named language.
+// Variables should already be expanded.
+func Generate(language string, req *models.Request) (string, error) {
+	for _, g := range generators {
+		if g.name != language {
+			continue
+		}
+		r, err := newRequest(req)
+		if err != nil {
+			return "", err
+		}
+		return g.generate(r), nil
+	}
+	return "", fmt.Errorf("unknown language %q", language)
+}
+
+// request is the sent form of a models.Request, as built by the HTTP client
+type request struct {
+	method  string
+	url     string
+	headers []models.Header
+	body    string
+	// json is the decoded body when it is a JSON document, preserving key order
+	json any
+}
+
+// newRequest resolves the URL, headers and body the HTTP client would send
+func newRequest(req *models.Request) (*request, error) {
+	if req.IsWebSocket() || req.IsGRPC() {
+		return nil, errors.New("code generation supports HTTP requests only")
+	}
+	if req.URL == "" {
+		return nil, errors.New("URL is required")
+	}
+
+	r := &request{method: req.Method, url: req.URL, body: req.Body}
+	if !strings.HasPrefix(r.url, "http://") && !strings.HasPrefix(r.url, "https://") {
+		r.url = "http://" + r.url
+	}
+
+	switch {
+	case req.IsGraphQL() && req.Method == "GET":
+		encoded, err := httpclient.GraphQLQueryString(r.url, req.GraphQL)
+		if err != nil {
+			return nil, err
+		}
+		r.url = encoded
+		r.body = ""
+	case req.IsGraphQL():
+		payload, err := httpclient.GraphQLPayload(req.GraphQL)
+		if err != nil {
+			return nil, err
+		}
+		r.body = string(payload)
+	}
+
+	for _, h := range req.Headers {
+		if h.Enabled && h.Key != "" {
+			r.headers = append(r.headers, h)
+		}
+	}
+	// Match the client's default Content-Type for requests with a body
+	if r.body != "" && r.header("Content-Type") == "" {
+		r.headers = append(r.headers, models.Header{Key: "Content-Type", Value: "application/json", Enabled: true})
+	}
+
+	if r.body != "" && isJSONType(r.header("Content-Type")) {
+		if v, err := decodeJSON(r.body); err == nil {
+			r.json = v
+		}
+	}
+	return r, nil
+}
+
+// header returns the value of the named header, or "" if it is not set
+func (r *request) header(name string) string {
+	for _, h := range r.headers {
+		if strings.EqualFold(h.Key, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// isJSONType reports whether a Content-Type names a JSON media type
+func isJSONType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// member is a key and value of a JSON object
+type member struct {
+	key   string
+	value any
+}
+
+// object is a JSON object with its keys in document order
+type object []member
+
+// decodeJSON decodes a JSON document into nil, bool, json.Number, string,
+// []any and object values
+func decodeJSON(s string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+// decodeValue decodes the next value from dec
+func decodeValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key.(string), value})
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []any{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+// literalStyle describes how a language writes JSON-like literals
+type literalStyle struct {
+	null, yes, no string
+	indent        string
+	// key formats an object key
+	key func(string) string
+}
+
+// literal writes v as a nested literal starting at the given depth
+func (s literalStyle) literal(v any, depth int) string {
+	pad := strings.Repeat(s.indent, depth)
+	inner := pad + s.indent
+	switch v := v.(type) {
+	case nil:
+		return s.null
+	case bool:
+		if v {
+			return s.yes
+		}
+		return s.no
+	case json.Number:
+		return v.String()
+	case string:
+		return quote(v)
+	case []any:
+		if len(v) == 0 {
+			return "[]"
+		}
+		// Lists of scalars stay on one line
+		if scalars(v) {
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = s.literal(item, depth)
+			}
+			return "[" + strings.Join(items, ", ") + "]"
+		}
+		var b strings.Builder
+		b.WriteString("[\n")
+		for _, item := range v {
+			b.WriteString(inner + s.literal(item, depth+1) + ",\n")
+		}
+		b.WriteString(pad + "]")
+		return b.String()
+	case object:
+		if len(v) == 0 {
+			return "{}"
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, m := range v {
+			b.WriteString(inner + s.key(m.key) + ": " + s.literal(m.value, depth+1) + ",\n")
+		}
+		b.WriteString(pad + "}")
+		return b.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// scalars reports whether a list holds no objects or lists
+func scalars(list []any) bool {
+	for _, item := range list {
+		switch item.(type) {
+		case []any, object:
+			return false
+		}
+	}
+	return true
+}
+
+// quote writes s as a double-quoted string using escapes shared by Python,
+// JavaScript and Java
+func quote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// shellQuote writes s as a single-quoted POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powerShellQuote writes s as a single-quoted PowerShell string
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,127 @@
+package codegen
+
+import (
+	"flag"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"percentman/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// testRequests are the requests every generator is checked against
+var testRequests = []struct {
+	name string
+	req  models.Request
+}{
+	{"get", models.Request{
+		Method:  "GET",
+		URL:     "https://api.example.com/users?page=2",
+		Headers: []models.Header{{Key: "Accept", Value: "application/json", Enabled: true}},
+	}},
+	{"json", models.Request{
+		Method: "POST",
+		URL:    "https://api.example.com/users",
+		Headers: []models.Header{
+			{Key: "Content-Type", Value: "application/json", Enabled: true},
+			{Key: "Authorization", Value: "Bearer token", Enabled: true},
+			{Key: "X-Disabled", Value: "ignored", Enabled: false},
+		},
+		Body: `{"name": "Ada", "tags": ["admin", "ops"], "address": {"city": "London", "zip": null}, "active": true}`,
+	}},
+	{"multiline", models.Request{
+		Method:  "PUT",
+		URL:     "https://api.example.com/notes/1",
+		Headers: []models.Header{{Key: "Content-Type", Value: "text/plain", Enabled: true}},
+		Body:    "first line\nit's the \"second\" line\n",
+	}},
+	{"crlf", models.Request{
+		Method:  "POST",
+		URL:     "https://api.example.com/upload",
+		Headers: []models.Header{{Key: "Content-Type", Value: "text/csv", Enabled: true}},
+		Body:    "id,name\r\n1,Ada\r\n",
+	}},
+	{"backtick", models.Request{
+		Method:  "PATCH",
+		URL:     "https://api.example.com/snippets/1",
+		Headers: []models.Header{{Key: "Content-Type", Value: "text/markdown", Enabled: true}},
+		Body:    "Run `make`\nthen test\n",
+	}},
+}
+
+// fileName turns a language name into part of a file name
+func fileName(language string) string {
+	slug := regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(language), "-")
+	return strings.Trim(slug, "-")
+}
+
+func TestGenerateGolden(t *testing.T) {
+	for _, language := range Languages() {
+		for _, tc := range testRequests {
+			t.Run(fileName(language)+"/"+tc.name, func(t *testing.T) {
+				got, err := Generate(language, &tc.req)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				golden := filepath.Join("testdata", tc.name+"."+fileName(language)+".golden")
+				if *update {
+					if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != string(want) {
+					t.Errorf("snippet differs from %s:\n%s", golden, got)
+				}
+			})
+		}
+	}
+}
+
+func TestGoBodyLiteral(t *testing.T) {
+	for _, tc := range testRequests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := Generate("Go (net/http)", &tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted, err := format.Source([]byte(code)); err != nil || string(formatted) != code {
+				t.Fatalf("generated Go is not gofmt-formatted (%v):\n%s", err, code)
+			}
+
+			r, err := newRequest(&tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.body == "" {
+				return
+			}
+			_, after, ok := strings.Cut(code, "strings.NewReader(")
+			if !ok {
+				t.Fatalf("no body in:\n%s", code)
+			}
+			literal := after[:strings.Index(after, ")\n")]
+			body, err := strconv.Unquote(literal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The compiler drops carriage returns from raw strings
+			if strings.HasPrefix(literal, "`") {
+				body = strings.ReplaceAll(body, "\r", "")
+			}
+			if body != r.body {
+				t.Errorf("body literal %s sends %q, want %q", literal, body, r.body)
+			}
+		})
+	}
+}
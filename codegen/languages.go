@@ -0,0 +1,324 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// identifierPattern matches object keys JavaScript accepts without quotes
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+
+var (
+	pythonLiterals = literalStyle{null: "None", yes: "True", no: "False", indent: "    ", key: quote}
+	jsLiterals     = literalStyle{null: "null", yes: "true", no: "false", indent: "  ", key: jsKey}
+)
+
+// jsKey writes an object key, quoting it only when necessary
+func jsKey(key string) string {
+	if identifierPattern.MatchString(key) {
+		return key
+	}
+	return quote(key)
+}
+
+// curl writes a curl command line
+func curl(r *request) string {
+	parts := []string{"curl", shellQuote(r.url)}
+	switch {
+	case r.method == "HEAD":
+		parts = append(parts, "--head")
+	case r.method != "GET" || r.body != "":
+		parts = append(parts, "-X "+r.method)
+	}
+	for _, h := range r.headers {
+		parts = append(parts, "-H "+shellQuote(h.Key+": "+h.Value))
+	}
+	if r.body != "" {
+		parts = append(parts, "--data-raw "+shellQuote(r.body))
+	}
+	if len(parts) == 2 {
+		return strings.Join(parts, " ") + "\n"
+	}
+	return strings.Join(parts[:2], " ") + " \\\n  " + strings.Join(parts[2:], " \\\n  ") + "\n"
+}
+
+// goString writes s as a Go string literal. Multi-line text is written as a
+// raw string unless it holds a backtick, or a carriage return, which the
+// compiler drops from raw strings.
+func goString(s string) string {
+	if strings.Contains(s, "\n") && !strings.ContainsAny(s, "`\r") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// goMethods maps methods to their net/http constants
+var goMethods = map[string]string{
+	"GET": "http.MethodGet", "POST": "http.MethodPost", "PUT": "http.MethodPut",
+	"PATCH": "http.MethodPatch", "DELETE": "http.MethodDelete", "HEAD": "http.MethodHead",
+	"OPTIONS": "http.MethodOptions", "CONNECT": "http.MethodConnect", "TRACE": "http.MethodTrace",
+}
+
+// goNetHTTP writes a Go program using net/http
+func goNetHTTP(r *request) string {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n")
+	if r.body != "" {
+		b.WriteString("\t\"strings\"\n")
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+
+	body := "nil"
+	if r.body != "" {
+		fmt.Fprintf(&b, "\tbody := strings.NewReader(%s)\n", goString(r.body))
+		body = "body"
+	}
+	method, ok := goMethods[r.method]
+	if !ok {
+		method = strconv.Quote(r.method)
+	}
+	fmt.Fprintf(&b, "\treq, err := http.NewRequest(%s, %s, %s)\n", method, strconv.Quote(r.url), body)
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	for _, h := range r.headers {
+		fmt.Fprintf(&b, "\treq.Header.Set(%s, %s)\n", strconv.Quote(h.Key), strconv.Quote(h.Value))
+	}
+
+	b.WriteString("\n\tresp, err := http.DefaultClient.Do(req)\n")
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	b.WriteString("\tdefer resp.Body.Close()\n\n")
+	b.WriteString("\tdata, err := io.ReadAll(resp.Body)\n")
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	b.WriteString("\tfmt.Println(resp.Status)\n")
+	b.WriteString("\tfmt.Println(string(data))\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// pythonMethods are the methods with a requests shortcut function
+var pythonMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "HEAD": true, "OPTIONS": true,
+}
+
+// pythonRequests writes a Python script using requests
+func pythonRequests(r *request) string {
+	var b strings.Builder
+	b.WriteString("import requests\n\n")
+	fmt.Fprintf(&b, "url = %s\n", quote(r.url))
+	args := []string{"url"}
+	if !pythonMethods[r.method] {
+		args = []string{quote(r.method), "url"}
+	}
+
+	if len(r.headers) > 0 {
+		b.WriteString("headers = {\n")
+		for _, h := range r.headers {
+			fmt.Fprintf(&b, "    %s: %s,\n", quote(h.Key), quote(h.Value))
+		}
+		b.WriteString("}\n")
+		args = append(args, "headers=headers")
+	}
+	switch {
+	case r.json != nil:
+		fmt.Fprintf(&b, "payload = %s\n", pythonLiterals.literal(r.json, 0))
+		args = append(args, "json=payload")
+	case r.body != "":
+		fmt.Fprintf(&b, "payload = %s\n", quote(r.body))
+		args = append(args, "data=payload")
+	}
+
+	function := "requests.request"
+	if pythonMethods[r.method] {
+		function = "requests." + strings.ToLower(r.method)
+	}
+	fmt.Fprintf(&b, "\nresponse = %s(%s)\n", function, strings.Join(args, ", "))
+	b.WriteString("print(response.status_code)\n")
+	b.WriteString("print(response.text)\n")
+	return b.String()
+}
+
+// jsHeaders writes a headers object literal at the given indent
+func jsHeaders(r *request, indent string) string {
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, h := range r.headers {
+		fmt.Fprintf(&b, "%s  %s: %s,\n", indent, quote(h.Key), quote(h.Value))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// javaScriptFetch writes a JavaScript snippet using fetch
+func javaScriptFetch(r *request) string {
+	var b strings.Builder
+	if r.method == "GET" && len(r.headers) == 0 && r.body == "" {
+		fmt.Fprintf(&b, "const response = await fetch(%s);\n", quote(r.url))
+	} else {
+		fmt.Fprintf(&b, "const response = await fetch(%s, {\n", quote(r.url))
+		fmt.Fprintf(&b, "  method: %s,\n", quote(r.method))
+		if len(r.headers) > 0 {
+			fmt.Fprintf(&b, "  headers: %s,\n", jsHeaders(r, "  "))
+		}
+		switch {
+		case r.json != nil:
+			fmt.Fprintf(&b, "  body: JSON.stringify(%s),\n", jsLiterals.literal(r.json, 1))
+		case r.body != "":
+			fmt.Fprintf(&b, "  body: %s,\n", quote(r.body))
+		}
+		b.WriteString("});\n")
+	}
+	b.WriteString("\nconsole.log(response.status);\n")
+	b.WriteString("console.log(await response.text());\n")
+	return b.String()
+}
+
+// nodeAxios writes a Node.js snippet using axios
+func nodeAxios(r *request) string {
+	var b strings.Builder
+	b.WriteString("const axios = require(\"axios\");\n\n")
+	b.WriteString("axios({\n")
+	fmt.Fprintf(&b, "  method: %s,\n", quote(strings.ToLower(r.method)))
+	fmt.Fprintf(&b, "  url: %s,\n", quote(r.url))
+	if len(r.headers) > 0 {
+		fmt.Fprintf(&b, "  headers: %s,\n", jsHeaders(r, "  "))
+	}
+	switch {
+	case r.json != nil:
+		fmt.Fprintf(&b, "  data: %s,\n", jsLiterals.literal(r.json, 1))
+	case r.body != "":
+		fmt.Fprintf(&b, "  data: %s,\n", quote(r.body))
+	}
+	b.WriteString("})\n")
+	b.WriteString("  .then((response) => {\n")
+	b.WriteString("    console.log(response.status);\n")
+	b.WriteString("    console.log(response.data);\n")
+	b.WriteString("  })\n")
+	b.WriteString("  .catch((error) => {\n")
+	b.WriteString("    console.error(error);\n")
+	b.WriteString("  });\n")
+	return b.String()
+}
+
+// javaString writes s as a Java string literal, using a text block for multi-line text
+func javaString(s string, indent string) string {
+	if !strings.Contains(s, "\n") {
+		return quote(s)
+	}
+	escaped := strings.ReplaceAll(s, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, `"""`, `\"""`)
+	lines := strings.Split(escaped, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	// A closing delimiter on the same line as the text avoids a trailing newline
+	return "\"\"\"\n" + strings.Join(lines, "\n") + "\"\"\""
+}
+
+// javaHTTPClient writes a Java program using java.net.http.HttpClient
+func javaHTTPClient(r *request) string {
+	var b strings.Builder
+	b.WriteString("import java.net.URI;\n")
+	b.WriteString("import java.net.http.HttpClient;\n")
+	b.WriteString("import java.net.http.HttpRequest;\n")
+	b.WriteString("import java.net.http.HttpResponse;\n\n")
+	b.WriteString("public class Main {\n")
+	b.WriteString("    public static void main(String[] args) throws Exception {\n")
+	b.WriteString("        HttpClient client = HttpClient.newHttpClient();\n")
+	b.WriteString("        HttpRequest request = HttpRequest.newBuilder()\n")
+	fmt.Fprintf(&b, "                .uri(URI.create(%s))\n", quote(r.url))
+	for _, h := range r.headers {
+		fmt.Fprintf(&b, "                .header(%s, %s)\n", quote(h.Key), quote(h.Value))
+	}
+
+	publisher := "HttpRequest.BodyPublishers.noBody()"
+	if r.body != "" {
+		publisher = "HttpRequest.BodyPublishers.ofString(" + javaString(r.body, "                        ") + ")"
+	}
+	switch {
+	case r.method == "GET" && r.body == "":
+		b.WriteString("                .GET()\n")
+	case r.method == "DELETE" && r.body == "":
+		b.WriteString("                .DELETE()\n")
+	case (r.method == "POST" || r.method == "PUT") && r.body != "":
+		fmt.Fprintf(&b, "                .%s(%s)\n", r.method, publisher)
+	default:
+		fmt.Fprintf(&b, "                .method(%s, %s)\n", quote(r.method), publisher)
+	}
+	b.WriteString("                .build();\n\n")
+	b.WriteString("        HttpResponse<String> response = client.send(request, HttpResponse.BodyHandlers.ofString());\n")
+	b.WriteString("        System.out.println(response.statusCode());\n")
+	b.WriteString("        System.out.println(response.body());\n")
+	b.WriteString("    }\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// httpie writes an HTTPie command line
+func httpie(r *request) string {
+	parts := []string{"http", r.method, shellQuote(r.url)}
+	for _, h := range r.headers {
+		parts = append(parts, shellQuote(h.Key+":"+h.Value))
+	}
+	if r.body != "" {
+		parts = append(parts, "--raw "+shellQuote(r.body))
+	}
+	if len(parts) == 3 {
+		return strings.Join(parts, " ") + "\n"
+	}
+	return strings.Join(parts[:3], " ") + " \\\n  " + strings.Join(parts[3:], " \\\n  ") + "\n"
+}
+
+// powerShellMethods maps methods to Invoke-WebRequest -Method values
+var powerShellMethods = map[string]string{
+	"GET": "Get", "POST": "Post", "PUT": "Put", "PATCH": "Patch", "DELETE": "Delete",
+	"HEAD": "Head", "OPTIONS": "Options", "TRACE": "Trace",
+}
+
+// powerShell writes a PowerShell snippet using Invoke-WebRequest
+func powerShell(r *request) string {
+	var b strings.Builder
+	args := []string{"-Uri " + powerShellQuote(r.url)}
+	if method, ok := powerShellMethods[r.method]; ok {
+		args = append(args, "-Method "+method)
+	} else {
+		args = append(args, "-CustomMethod "+powerShellQuote(r.method))
+	}
+
+	// Content-Type is passed with -ContentType, which Windows PowerShell requires
+	var headers []string
+	contentType := ""
+	for _, h := range r.headers {
+		if strings.EqualFold(h.Key, "Content-Type") {
+			contentType = h.Value
+			continue
+		}
+		headers = append(headers, fmt.Sprintf("    %s = %s\n", powerShellQuote(h.Key), powerShellQuote(h.Value)))
+	}
+	if len(headers) > 0 {
+		b.WriteString("$headers = @{\n" + strings.Join(headers, "") + "}\n")
+		args = append(args, "-Headers $headers")
+	}
+	if r.body != "" {
+		// A here-string keeps the body verbatim unless a line starts with its terminator
+		if strings.Contains("\n"+r.body, "\n'@") {
+			fmt.Fprintf(&b, "$body = %s\n", powerShellQuote(r.body))
+		} else {
+			fmt.Fprintf(&b, "$body = @'\n%s\n'@\n", r.body)
+		}
+		args = append(args, "-Body $body")
+	}
+	if contentType != "" {
+		args = append(args, "-ContentType "+powerShellQuote(contentType))
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "$response = Invoke-WebRequest %s\n", strings.Join(args, " "))
+	b.WriteString("$response.StatusCode\n")
+	b.WriteString("$response.Content\n")
+	return b.String()
+}
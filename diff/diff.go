@@ -0,0 +1,120 @@
+// Package diff compares texts line by line.
+package diff
+
+import "strings"
+
+// Op says how a line differs between the old and the new text
+type Op int
+
+const (
+	// Equal lines are in both texts
+	Equal Op = iota
+	// Delete lines are only in the old text
+	Delete
+	// Insert lines are only in the new text
+	Insert
+)
+
+// Line is one line of a comparison
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// maxCells bounds the work spent on finding the smallest difference. Larger
+// changes are shown as the old lines replaced by the new ones.
+const maxCells = 2000 * 2000
+
+// Lines compares the lines of a and b. The result lists every line of both
+// texts in order, with as few deleted and inserted lines as it can find.
+func Lines(a, b string) []Line {
+	x := splitLines(a)
+	y := splitLines(b)
+
+	// Most edits touch a few lines, so the common start and end are skipped
+	var head, tail []Line
+	for len(x) > 0 && len(y) > 0 && x[0] == y[0] {
+		head = append(head, Line{Equal, x[0]})
+		x, y = x[1:], y[1:]
+	}
+	for len(x) > 0 && len(y) > 0 && x[len(x)-1] == y[len(y)-1] {
+		tail = append(tail, Line{Equal, x[len(x)-1]})
+		x, y = x[:len(x)-1], y[:len(y)-1]
+	}
+
+	result := head
+	if len(x)*len(y) > maxCells {
+		for _, s := range x {
+			result = append(result, Line{Delete, s})
+		}
+		for _, s := range y {
+			result = append(result, Line{Insert, s})
+		}
+	} else {
+		result = append(result, lcs(x, y)...)
+	}
+	for i := len(tail) - 1; i >= 0; i-- {
+		result = append(result, tail[i])
+	}
+	return result
+}
+
+// Changed reports whether a comparison found any differences
+func Changed(lines []Line) bool {
+	for _, l := range lines {
+		if l.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// lcs compares x and y through their longest common subsequence
+func lcs(x, y []string) []Line {
+	// length[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	length := make([][]int, len(x)+1)
+	for i := range length {
+		length[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				length[i][j] = length[i+1][j+1] + 1
+			} else {
+				length[i][j] = max(length[i+1][j], length[i][j+1])
+			}
+		}
+	}
+
+	var result []Line
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			result = append(result, Line{Equal, x[i]})
+			i++
+			j++
+		case length[i+1][j] >= length[i][j+1]:
+			result = append(result, Line{Delete, x[i]})
+			i++
+		default:
+			result = append(result, Line{Insert, y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		result = append(result, Line{Delete, x[i]})
+	}
+	for ; j < len(y); j++ {
+		result = append(result, Line{Insert, y[j]})
+	}
+	return result
+}
+
+// splitLines splits text into lines, ignoring a final line break
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,442 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"percentman/models"
+)
+
+// templateDepth limits how deeply nested messages are filled in message templates
+const templateDepth = 3
+
+// Client invokes gRPC methods described by server reflection or .proto files.
+// Deadlines are taken from the context passed to each call.
+type Client struct{}
+
+// NewClient creates a new gRPC client
+func NewClient() *Client {
+	return &Client{}
+}
+
+// Method describes a callable gRPC method
+type Method struct {
+	Service         string
+	Name            string
+	InputType       string
+	OutputType      string
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+// FullName returns the method as "package.Service/Method"
+func (m Method) FullName() string {
+	return m.Service + "/" + m.Name
+}
+
+// descriptorSource finds service descriptors
+type descriptorSource interface {
+	ListServices() ([]string, error)
+	ResolveService(name string) (*desc.ServiceDescriptor, error)
+}
+
+// fileSource serves descriptors parsed from .proto files
+type fileSource struct {
+	services map[string]*desc.ServiceDescriptor
+}
+
+func (f *fileSource) ListServices() ([]string, error) {
+	names := make([]string, 0, len(f.services))
+	for name := range f.services {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (f *fileSource) ResolveService(name string) (*desc.ServiceDescriptor, error) {
+	sd, ok := f.services[name]
+	if !ok {
+		return nil, fmt.Errorf("service %q is not defined in the loaded .proto files", name)
+	}
+	return sd, nil
+}
+
+// session is an open connection with its descriptor source
+type session struct {
+	conn   *grpc.ClientConn
+	source descriptorSource
+	close  func()
+}
+
+// open dials the request's target and prepares a descriptor source
+func (c *Client) open(ctx context.Context, req *models.Request) (*session, error) {
+	if req.URL == "" {
+		return nil, errors.New("URL is required")
+	}
+	options := requestOptions(req)
+
+	creds := credentials.NewTLS(&tls.Config{})
+	if options.Plaintext {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(target(req.URL), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &session{conn: conn, close: func() { conn.Close() }}
+	if len(options.ProtoFiles) > 0 {
+		source, err := parseProtoFiles(options.ProtoFiles, options.ImportPaths)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		s.source = source
+	} else {
+		refClient := grpcreflect.NewClientAuto(outgoingContext(ctx, req), conn)
+		s.source = refClient
+		s.close = func() {
+			refClient.Reset()
+			conn.Close()
+		}
+	}
+	return s, nil
+}
+
+// requestOptions returns the gRPC options of a request, never nil
+func requestOptions(req *models.Request) *models.GRPCRequest {
+	if req.GRPC == nil {
+		return &models.GRPCRequest{}
+	}
+	return req.GRPC
+}
+
+// target strips any URL scheme, leaving host:port
+func target(url string) string {
+	for _, prefix := range []string{"grpc://", "grpcs://", "http://", "https://"} {
+		url = strings.TrimPrefix(url, prefix)
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+// outgoingContext attaches the request's enabled headers as metadata
+func outgoingContext(ctx context.Context, req *models.Request) context.Context {
+	md := metadata.MD{}
+	for _, h := range req.Headers {
+		if h.Enabled && h.Key != "" {
+			md.Append(strings.ToLower(h.Key), h.Value)
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// parseProtoFiles parses .proto files; without import paths each file's own directory is used
+func parseProtoFiles(files, importPaths []string) (*fileSource, error) {
+	paths := append([]string(nil), importPaths...)
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file
+		if len(importPaths) == 0 {
+			paths = append(paths, filepath.Dir(file))
+			names[i] = filepath.Base(file)
+			continue
+		}
+		for _, dir := range importPaths {
+			if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+				names[i] = filepath.ToSlash(rel)
+				break
+			}
+		}
+	}
+
+	parser := protoparse.Parser{ImportPaths: paths}
+	fds, err := parser.ParseFiles(names...)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &fileSource{services: make(map[string]*desc.ServiceDescriptor)}
+	for _, fd := range fds {
+		for _, sd := range fd.GetServices() {
+			source.services[sd.GetFullyQualifiedName()] = sd
+		}
+	}
+	return source, nil
+}
+
+// ListMethods discovers every method offered by the target
+func (c *Client) ListMethods(ctx context.Context, req *models.Request) ([]Method, error) {
+	s, err := c.open(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+
+	services, err := s.source.ListServices()
+	if err != nil {
+		return nil, fmt.Errorf("listing services: %w", err)
+	}
+	sort.Strings(services)
+
+	var methods []Method
+	for _, name := range services {
+		// The reflection service itself is not interesting to call
+		if strings.HasPrefix(name, "grpc.reflection.") {
+			continue
+		}
+		sd, err := s.source.ResolveService(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, md := range sd.GetMethods() {
+			methods = append(methods, Method{
+				Service:         name,
+				Name:            md.GetName(),
+				InputType:       md.GetInputType().GetFullyQualifiedName(),
+				OutputType:      md.GetOutputType().GetFullyQualifiedName(),
+				ClientStreaming: md.IsClientStreaming(),
+				ServerStreaming: md.IsServerStreaming(),
+			})
+		}
+	}
+	return methods, nil
+}
+
+// resolveMethod finds the descriptor of the request's method
+func resolveMethod(s *session, req *models.Request) (*desc.MethodDescriptor, error) {
+	options := requestOptions(req)
+	if options.Service == "" || options.Method == "" {
+		return nil, errors.New("select a service method")
+	}
+	sd, err := s.source.ResolveService(options.Service)
+	if err != nil {
+		return nil, err
+	}
+	md := sd.FindMethodByName(options.Method)
+	if md == nil {
+		return nil, fmt.Errorf("service %s has no method %s", options.Service, options.Method)
+	}
+	return md, nil
+}
+
+// MessageTemplate returns a JSON skeleton of the request method's input message
+func (c *Client) MessageTemplate(ctx context.Context, req *models.Request) (string, error) {
+	s, err := c.open(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	defer s.close()
+
+	md, err := resolveMethod(s, req)
+	if err != nil {
+		return "", err
+	}
+
+	msg := dynamicpb.NewMessage(md.GetInputType().UnwrapMessage())
+	fillMessage(msg, templateDepth)
+
+	data, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return indentJSON(data), nil
+}
+
+// indentJSON formats protojson output, whose whitespace is deliberately unstable
+func indentJSON(data []byte) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
+
+// fillMessage sets nested singular message fields so they appear in templates
+func fillMessage(msg protoreflect.Message, depth int) {
+	if depth == 0 {
+		return
+	}
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() || fd.ContainingOneof() != nil {
+			continue
+		}
+		// Well-known types have special JSON forms that an empty message would misrepresent
+		if strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf.") {
+			continue
+		}
+		child := msg.Mutable(fd).Message()
+		fillMessage(child, depth-1)
+	}
+}
+
+// errorKind returns the models.ErrorKind constant describing why a call could
+// not be made. gRPC reports connection failures as Unavailable with the cause
+// only in the message.
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return models.ErrorKindCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return models.ErrorKindTimeout
+	}
+
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.Canceled:
+		return models.ErrorKindCanceled
+	case codes.DeadlineExceeded:
+		return models.ErrorKindTimeout
+	case codes.Unavailable:
+		msg := st.Message()
+		switch {
+		case strings.Contains(msg, "no such host"), strings.Contains(msg, "lookup "):
+			return models.ErrorKindDNS
+		case strings.Contains(msg, "tls:"), strings.Contains(msg, "x509:"):
+			return models.ErrorKindTLS
+		}
+		return models.ErrorKindConnect
+	}
+	return models.ErrorKindOther
+}
+
+// Invoke calls the request's method with the JSON message in req.Body.
+// Server-streaming responses are passed to onMessage as they arrive.
+func (c *Client) Invoke(ctx context.Context, req *models.Request, onMessage func(string)) *models.Response {
+	response := &models.Response{}
+
+	s, err := c.open(ctx, req)
+	if err != nil {
+		response.Error = err.Error()
+		response.ErrorKind = errorKind(err)
+		return response
+	}
+	defer s.close()
+
+	md, err := resolveMethod(s, req)
+	if err != nil {
+		response.Error = err.Error()
+		response.ErrorKind = errorKind(err)
+		return response
+	}
+	if md.IsClientStreaming() {
+		response.Error = "Client-streaming methods are not supported"
+		response.ErrorKind = models.ErrorKindOther
+		return response
+	}
+
+	input := dynamicpb.NewMessage(md.GetInputType().UnwrapMessage())
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		body = "{}"
+	}
+	if err := protojson.Unmarshal([]byte(body), input); err != nil {
+		response.Error = "Invalid request message: " + err.Error()
+		response.ErrorKind = models.ErrorKindOther
+		return response
+	}
+
+	outputType := md.GetOutputType().UnwrapMessage()
+	fullMethod := "/" + md.GetService().GetFullyQualifiedName() + "/" + md.GetName()
+	callCtx := outgoingContext(ctx, req)
+
+	var header, trailer metadata.MD
+	var messages []string
+	startTime := time.Now()
+
+	if md.IsServerStreaming() {
+		var stream grpc.ClientStream
+		stream, err = s.conn.NewStream(callCtx, &grpc.StreamDesc{ServerStreams: true}, fullMethod)
+		if err == nil {
+			err = stream.SendMsg(input)
+		}
+		if err == nil {
+			err = stream.CloseSend()
+		}
+		for err == nil {
+			output := dynamicpb.NewMessage(outputType)
+			if err = stream.RecvMsg(output); err != nil {
+				break
+			}
+			data, _ := protojson.Marshal(output)
+			text := indentJSON(data)
+			messages = append(messages, text)
+			if onMessage != nil {
+				onMessage(text)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		if stream != nil {
+			header, _ = stream.Header()
+			trailer = stream.Trailer()
+		}
+	} else {
+		output := dynamicpb.NewMessage(outputType)
+		err = s.conn.Invoke(callCtx, fullMethod, input, output, grpc.Header(&header), grpc.Trailer(&trailer))
+		if err == nil {
+			data, _ := protojson.Marshal(output)
+			messages = append(messages, indentJSON(data))
+		}
+	}
+	response.ResponseTime = time.Since(startTime)
+
+	st := status.Convert(err)
+	response.StatusCode = int(st.Code())
+	response.Status = fmt.Sprintf("%d %s", st.Code(), st.Code())
+	if st.Message() != "" && err != nil {
+		response.Status += ": " + st.Message()
+	}
+	response.Headers = flattenMetadata(header)
+	response.Trailers = flattenMetadata(trailer)
+
+	// A call that never reached the server, or ran out of time, failed like
+	// an HTTP request would; other codes are the server's answer
+	if kind := errorKind(err); err != nil && kind != models.ErrorKindOther {
+		response.Error = response.Status
+		response.ErrorKind = kind
+	}
+
+	if md.IsServerStreaming() {
+		response.Body = "[" + strings.Join(messages, ",\n") + "]"
+	} else if len(messages) > 0 {
+		response.Body = messages[0]
+	}
+	response.Size = int64(len(response.Body))
+	response.DecodedSize = response.Size
+
+	return response
+}
+
+// flattenMetadata joins multi-valued metadata like HTTP headers
+func flattenMetadata(md metadata.MD) map[string]string {
+	result := make(map[string]string, len(md))
+	for k, v := range md {
+		result[k] = strings.Join(v, ", ")
+	}
+	return result
+}
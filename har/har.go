@@ -0,0 +1,460 @@
+// Package har reads and writes HTTP Archive (HAR) 1.2 files.
+package har
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"percentman/models"
+)
+
+// creatorName identifies percentman in exported files
+const creatorName = "percentman"
+
+// HAR is the top-level object of a HAR file
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+// Log holds the entries of a HAR file
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator names the application that wrote the file
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is one request and its response
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Time is the total time in milliseconds
+	Time     float64  `json:"time"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	Cache    struct{} `json:"cache"`
+	Timings  Timings  `json:"timings"`
+}
+
+// Request is a HAR request
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// Response is a HAR response
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+	// Error is why a request got no response, as browsers record it
+	Error string `json:"_error,omitempty"`
+}
+
+// NameValue is a header or query parameter
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Cookie is a request or response cookie
+type Cookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+// PostData is a request body
+type PostData struct {
+	MimeType string      `json:"mimeType"`
+	Text     string      `json:"text"`
+	Params   []NameValue `json:"params,omitempty"`
+}
+
+// Content is a response body
+type Content struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Timings are phase durations in milliseconds; -1 means the phase does not apply
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// Parse decodes a HAR file
+func Parse(data []byte) (*HAR, error) {
+	var h HAR
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("invalid HAR file: %w", err)
+	}
+	if h.Log.Version == "" && len(h.Log.Entries) == 0 {
+		return nil, errors.New("invalid HAR file: no log entries")
+	}
+	return &h, nil
+}
+
+// History converts every entry to a history item
+func (h *HAR) History() []models.HistoryItem {
+	items := make([]models.HistoryItem, len(h.Log.Entries))
+	for i, e := range h.Log.Entries {
+		items[i] = models.HistoryItem{
+			Request:   *e.request(),
+			Response:  *e.response(),
+			Timestamp: e.StartedDateTime,
+		}
+	}
+	return items
+}
+
+// Templates converts every entry to a template named after its method and path,
+// grouped in one folder per host
+func (h *HAR) Templates() []models.Template {
+	templates := make([]models.Template, len(h.Log.Entries))
+	for i, e := range h.Log.Entries {
+		name := e.Request.Method + " " + e.Request.URL
+		folder := ""
+		if u, err := url.Parse(e.Request.URL); err == nil {
+			name = e.Request.Method + " " + u.Path
+			folder = u.Host
+		}
+		templates[i] = models.Template{
+			Name:    name,
+			Request: *e.request(),
+			Folder:  folder,
+		}
+	}
+	return templates
+}
+
+// request converts a HAR request. Cookies that have no Cookie header are added as one.
+func (e *Entry) request() *models.Request {
+	req := models.NewRequest()
+	req.Method = e.Request.Method
+	req.URL = e.Request.URL
+
+	hasCookieHeader := false
+	for _, h := range e.Request.Headers {
+		// HTTP/2 pseudo-headers are part of the request line, not headers
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		if strings.EqualFold(h.Name, "Cookie") {
+			hasCookieHeader = true
+		}
+		req.Headers = append(req.Headers, models.Header{Key: h.Name, Value: h.Value, Enabled: true})
+	}
+	if !hasCookieHeader && len(e.Request.Cookies) > 0 {
+		pairs := make([]string, len(e.Request.Cookies))
+		for i, c := range e.Request.Cookies {
+			pairs[i] = c.Name + "=" + c.Value
+		}
+		req.Headers = append(req.Headers, models.Header{Key: "Cookie", Value: strings.Join(pairs, "; "), Enabled: true})
+	}
+
+	if pd := e.Request.PostData; pd != nil {
+		req.Body = pd.Text
+		if req.Body == "" && len(pd.Params) > 0 {
+			form := url.Values{}
+			for _, p := range pd.Params {
+				form.Add(p.Name, p.Value)
+			}
+			req.Body = form.Encode()
+		}
+	}
+	return req
+}
+
+// errorKind maps a browser error such as "net::ERR_NAME_NOT_RESOLVED" to a
+// models.ErrorKind constant
+func errorKind(msg string) string {
+	switch {
+	case msg == "":
+		return ""
+	case strings.Contains(msg, "NAME_NOT_RESOLVED"), strings.Contains(msg, "no such host"):
+		return models.ErrorKindDNS
+	case strings.Contains(msg, "CERT"), strings.Contains(msg, "SSL"), strings.Contains(msg, "tls:"):
+		return models.ErrorKindTLS
+	case strings.Contains(msg, "TIMED_OUT"), strings.Contains(msg, "timeout"):
+		return models.ErrorKindTimeout
+	case strings.Contains(msg, "ABORTED"), strings.Contains(msg, "canceled"):
+		return models.ErrorKindCanceled
+	case strings.Contains(msg, "CONNECTION_"), strings.Contains(msg, "ADDRESS_UNREACHABLE"), strings.Contains(msg, "connection refused"):
+		return models.ErrorKindConnect
+	}
+	return models.ErrorKindOther
+}
+
+// response converts a HAR response. Base64 content is decoded, and the TLS
+// handshake, which HAR counts as part of connecting, is split out again.
+func (e *Entry) response() *models.Response {
+	r := e.Response
+	resp := &models.Response{
+		StatusCode:   r.Status,
+		Status:       strings.TrimSpace(strconv.Itoa(r.Status) + " " + r.StatusText),
+		Headers:      make(map[string]string),
+		ResponseTime: milliseconds(e.Time),
+		Proto:        r.HTTPVersion,
+		Error:        r.Error,
+		ErrorKind:    errorKind(r.Error),
+		Timings: &models.Timings{
+			Blocked: milliseconds(e.Timings.Blocked),
+			DNS:     milliseconds(e.Timings.DNS),
+			Connect: milliseconds(e.Timings.Connect - max(e.Timings.SSL, 0)),
+			TLS:     milliseconds(e.Timings.SSL),
+			Send:    milliseconds(e.Timings.Send),
+			Wait:    milliseconds(e.Timings.Wait),
+			Receive: milliseconds(e.Timings.Receive),
+		},
+	}
+
+	for _, h := range r.Headers {
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		key := http.CanonicalHeaderKey(h.Name)
+		if prev, ok := resp.Headers[key]; ok {
+			resp.Headers[key] = models.JoinHeader(key, []string{prev, h.Value})
+		} else {
+			resp.Headers[key] = h.Value
+		}
+	}
+	if _, ok := resp.Headers["Set-Cookie"]; !ok && len(r.Cookies) > 0 {
+		cookies := make([]string, len(r.Cookies))
+		for i, c := range r.Cookies {
+			cookies[i] = c.toHTTP().String()
+		}
+		resp.Headers["Set-Cookie"] = models.JoinHeader("Set-Cookie", cookies)
+	}
+
+	body := []byte(r.Content.Text)
+	if r.Content.Encoding == "base64" {
+		if decoded, err := base64.StdEncoding.DecodeString(r.Content.Text); err == nil {
+			body = decoded
+		}
+	}
+	resp.Body = string(body)
+	resp.RawBody = body
+	resp.DecodedSize = int64(len(body))
+	resp.Size = r.BodySize
+	if resp.Size < 0 {
+		resp.Size = resp.DecodedSize
+	}
+	return resp
+}
+
+func (c Cookie) toHTTP() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		HttpOnly: c.HTTPOnly,
+		Secure:   c.Secure,
+	}
+	if c.Expires != nil {
+		cookie.Expires = *c.Expires
+	}
+	return cookie
+}
+
+// milliseconds converts a HAR duration; negative values mean "not applicable"
+func milliseconds(ms float64) time.Duration {
+	if ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// Export writes history items as a HAR 1.2 file
+func Export(items []models.HistoryItem) ([]byte, error) {
+	h := HAR{Log: Log{
+		Version: "1.2",
+		Creator: Creator{Name: creatorName, Version: "1.0"},
+		Entries: make([]Entry, len(items)),
+	}}
+	for i, item := range items {
+		h.Log.Entries[i] = entry(item)
+	}
+	return json.MarshalIndent(h, "", "  ")
+}
+
+// entry converts a history item to a HAR entry
+func entry(item models.HistoryItem) Entry {
+	req, resp := item.Request, item.Response
+
+	proto := resp.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+
+	e := Entry{
+		StartedDateTime: item.Timestamp,
+		Time:            toMilliseconds(resp.ResponseTime),
+		Request: Request{
+			Method:      req.Method,
+			URL:         req.URL,
+			HTTPVersion: proto,
+			Cookies:     []Cookie{},
+			Headers:     []NameValue{},
+			QueryString: []NameValue{},
+			HeadersSize: -1,
+			BodySize:    int64(len(req.Body)),
+		},
+		Response: Response{
+			Status:      resp.StatusCode,
+			StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+			HTTPVersion: proto,
+			Cookies:     []Cookie{},
+			Headers:     []NameValue{},
+			HeadersSize: -1,
+			BodySize:    resp.Size,
+			Error:       resp.Error,
+		},
+	}
+
+	// Request headers, cookies, query string and body
+	contentType := ""
+	for _, h := range req.Headers {
+		if !h.Enabled || h.Key == "" {
+			continue
+		}
+		e.Request.Headers = append(e.Request.Headers, NameValue{Name: h.Key, Value: h.Value})
+		switch {
+		case strings.EqualFold(h.Key, "Cookie"):
+			if cookies, err := http.ParseCookie(h.Value); err == nil {
+				for _, c := range cookies {
+					e.Request.Cookies = append(e.Request.Cookies, Cookie{Name: c.Name, Value: c.Value})
+				}
+			}
+		case strings.EqualFold(h.Key, "Content-Type"):
+			contentType = h.Value
+		}
+	}
+	if u, err := url.Parse(req.URL); err == nil {
+		query := u.Query()
+		for _, name := range sortedKeys(query) {
+			for _, value := range query[name] {
+				e.Request.QueryString = append(e.Request.QueryString, NameValue{Name: name, Value: value})
+			}
+		}
+	}
+	if req.Body != "" {
+		e.Request.PostData = &PostData{MimeType: contentType, Text: req.Body}
+	}
+
+	// Response headers, cookies and content
+	for _, name := range sortedKeys(resp.Headers) {
+		for _, value := range models.SplitHeader(name, resp.Headers[name]) {
+			e.Response.Headers = append(e.Response.Headers, NameValue{Name: name, Value: value})
+			switch {
+			case strings.EqualFold(name, "Set-Cookie"):
+				if c, err := http.ParseSetCookie(value); err == nil {
+					e.Response.Cookies = append(e.Response.Cookies, fromHTTP(c))
+				}
+			case strings.EqualFold(name, "Location"):
+				e.Response.RedirectURL = value
+			}
+		}
+	}
+	e.Response.Content = Content{
+		Size:     int64(len(resp.Body)),
+		MimeType: resp.Headers["Content-Type"],
+		Text:     resp.Body,
+	}
+	if !utf8.ValidString(resp.Body) {
+		e.Response.Content.Text = base64.StdEncoding.EncodeToString([]byte(resp.Body))
+		e.Response.Content.Encoding = "base64"
+	}
+
+	// Timings; without recorded phases the whole duration counts as waiting
+	e.Timings = Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: e.Time}
+	if t := resp.Timings; t != nil {
+		e.Timings = Timings{
+			Blocked: optionalMilliseconds(t.Blocked),
+			DNS:     optionalMilliseconds(t.DNS),
+			Connect: optionalMilliseconds(t.Connect + t.TLS),
+			SSL:     optionalMilliseconds(t.TLS),
+			Send:    toMilliseconds(t.Send),
+			Wait:    toMilliseconds(t.Wait),
+			Receive: toMilliseconds(t.Receive),
+		}
+		e.Time = toMilliseconds(t.Blocked + t.DNS + t.Connect + t.TLS + t.Send + t.Wait + t.Receive)
+	}
+	return e
+}
+
+func fromHTTP(c *http.Cookie) Cookie {
+	cookie := Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		HTTPOnly: c.HttpOnly,
+		Secure:   c.Secure,
+	}
+	if !c.Expires.IsZero() {
+		expires := c.Expires
+		cookie.Expires = &expires
+	}
+	return cookie
+}
+
+func toMilliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// optionalMilliseconds reports phases that did not happen as -1
+func optionalMilliseconds(d time.Duration) float64 {
+	if d == 0 {
+		return -1
+	}
+	return toMilliseconds(d)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
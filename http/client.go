@@ -2,37 +2,190 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"percentman/models"
 )
 
+// maxClients is how many differently configured http.Clients are kept
+const maxClients = 8
+
 // Client handles HTTP requests
 type Client struct {
-	httpClient *http.Client
+	mu       sync.Mutex
+	defaults models.RequestSettings
+	// clients are configured per settings, so that requests sharing settings
+	// also share pooled connections; recent lists them, least recently used first
+	clients map[models.RequestSettings]*http.Client
+	recent  []models.RequestSettings
 }
 
 // NewClient creates a new HTTP client
 func NewClient() *Client {
 	return &Client{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		defaults: models.DefaultRequestSettings(),
+		clients:  make(map[models.RequestSettings]*http.Client),
+	}
+}
+
+// SetDefaults sets the settings used for requests without their own. The
+// client for the previous defaults lets its idle connections go.
+func (c *Client) SetDefaults(settings models.RequestSettings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if settings != c.defaults {
+		c.dropClient(c.defaults)
+	}
+	c.defaults = settings
+}
+
+// Defaults returns the settings used for requests without their own
+func (c *Client) Defaults() models.RequestSettings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.defaults
+}
+
+// clientFor returns an http.Client configured for the given settings. The
+// clients of the most recently used settings are kept; the least recently
+// used one lets its idle connections go when there are too many.
+func (c *Client) clientFor(settings models.RequestSettings) *http.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, ok := c.clients[settings]; ok {
+		c.touch(settings)
+		return client
+	}
+	if len(c.recent) >= maxClients {
+		c.dropClient(c.recent[0])
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   settings.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if settings.DisableKeepAlive {
+		dialer.KeepAlive = -1
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: settings.ResponseHeaderTimeout,
+		DisableKeepAlives:     settings.DisableKeepAlive,
+		ForceAttemptHTTP2:     !settings.DisableHTTP2,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		// Bodies are decoded by decodeBody so the raw bytes stay available
+		DisableCompression: true,
+	}
+	if settings.DisableHTTP2 {
+		// A non-nil, empty map prevents the transport from negotiating HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	client := &http.Client{
+		Timeout:   settings.Timeout,
+		Transport: transport,
+	}
+	c.clients[settings] = client
+	c.recent = append(c.recent, settings)
+	return client
+}
+
+// touch marks the client for settings as the most recently used. Callers hold c.mu.
+func (c *Client) touch(settings models.RequestSettings) {
+	i := slices.Index(c.recent, settings)
+	c.recent = append(slices.Delete(c.recent, i, i+1), settings)
+}
+
+// dropClient forgets the client for settings, if any, closing its idle
+// connections. Callers hold c.mu.
+func (c *Client) dropClient(settings models.RequestSettings) {
+	client, ok := c.clients[settings]
+	if !ok {
+		return
 	}
+	client.CloseIdleConnections()
+	delete(c.clients, settings)
+	c.recent = slices.DeleteFunc(c.recent, func(s models.RequestSettings) bool { return s == settings })
 }
 
 // SendRequest sends an HTTP request and returns the response
 func (c *Client) SendRequest(req *models.Request) *models.Response {
 	response := &models.Response{}
 
+	httpReq, err := newHTTPRequest(context.Background(), req)
+	if err != nil {
+		response.Error = err.Error()
+		response.ErrorKind = models.ErrorKindOther
+		return response
+	}
+
+	// Send request and measure time, recording each phase for the timings view
+	settings := req.EffectiveSettings(c.Defaults())
+	httpClient := c.clientFor(settings)
+	startTime := time.Now()
+	trace := &timingTrace{start: startTime}
+	httpReq = httpReq.WithContext(httptrace.WithClientTrace(httpReq.Context(), trace.clientTrace()))
+	httpResp, err := httpClient.Do(httpReq)
+	response.ResponseTime = time.Since(startTime)
+
+	if err != nil {
+		response.Error = err.Error()
+		response.ErrorKind = ErrorKind(err)
+		return response
+	}
+	defer httpResp.Body.Close()
+
+	// Read response
+	copyResponseHead(response, httpResp)
+
+	// Read response body
+	bodyBytes, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		response.Error = "Failed to read response body: " + err.Error()
+		response.ErrorKind = ErrorKind(err)
+		return response
+	}
+
+	response.RawBody = bodyBytes
+	response.Size = int64(len(bodyBytes))
+	response.ContentEncoding = httpResp.Header.Get("Content-Encoding")
+
+	// Decompress body; fall back to the raw bytes if decoding fails
+	decoded, truncated, err := decodeBody(bodyBytes, response.ContentEncoding, settings.DecodedSizeLimit())
+	response.Truncated = truncated
+	if err != nil {
+		response.DecodeError = "Failed to decode response body: " + err.Error()
+		decoded = bodyBytes
+	}
+	response.DecodedSize = int64(len(decoded))
+	response.Body = string(decoded)
+	response.Timings = trace.timings(time.Now())
+
+	return response
+}
+
+// newHTTPRequest builds a net/http request from the request model
+func newHTTPRequest(ctx context.Context, req *models.Request) (*http.Request, error) {
 	// Validate URL
 	if req.URL == "" {
-		response.Error = "URL is required"
-		return response
+		return nil, errors.New("URL is required")
 	}
 
 	// Add http:// if no protocol specified
@@ -43,15 +196,31 @@ func (c *Client) SendRequest(req *models.Request) *models.Response {
 
 	// Create request body
 	var body io.Reader
-	if req.Body != "" {
+	hasBody := req.Body != ""
+	switch {
+	case req.IsGraphQL() && req.Method == http.MethodGet:
+		// GraphQL over GET carries the request in the query string
+		encoded, err := GraphQLQueryString(url, req.GraphQL)
+		if err != nil {
+			return nil, err
+		}
+		url = encoded
+		hasBody = false
+	case req.IsGraphQL():
+		payload, err := GraphQLPayload(req.GraphQL)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(payload)
+		hasBody = true
+	case hasBody:
 		body = bytes.NewBufferString(req.Body)
 	}
 
 	// Create HTTP request
-	httpReq, err := http.NewRequest(req.Method, url, body)
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, url, body)
 	if err != nil {
-		response.Error = err.Error()
-		return response
+		return nil, err
 	}
 
 	// Add headers
@@ -62,43 +231,31 @@ func (c *Client) SendRequest(req *models.Request) *models.Response {
 	}
 
 	// Set default Content-Type for requests with body
-	if req.Body != "" && httpReq.Header.Get("Content-Type") == "" {
+	if hasBody && httpReq.Header.Get("Content-Type") == "" {
 		httpReq.Header.Set("Content-Type", "application/json")
 	}
 
-	// Send request and measure time
-	startTime := time.Now()
-	httpResp, err := c.httpClient.Do(httpReq)
-	response.ResponseTime = time.Since(startTime)
-
-	if err != nil {
-		response.Error = err.Error()
-		return response
+	// Advertise every encoding we can decode unless the user chose otherwise
+	if httpReq.Header.Get("Accept-Encoding") == "" {
+		httpReq.Header.Set("Accept-Encoding", defaultAcceptEncoding)
 	}
-	defer httpResp.Body.Close()
 
-	// Read response
+	return httpReq, nil
+}
+
+// copyResponseHead copies the status and headers of an HTTP response
+func copyResponseHead(response *models.Response, httpResp *http.Response) {
 	response.StatusCode = httpResp.StatusCode
 	response.Status = httpResp.Status
+	response.Proto = httpResp.Proto
 
 	// Copy response headers
 	response.Headers = make(map[string]string)
 	for k, v := range httpResp.Header {
 		if len(v) > 0 {
-			response.Headers[k] = strings.Join(v, ", ")
+			response.Headers[k] = models.JoinHeader(k, v)
 		}
 	}
-
-	// Read response body
-	bodyBytes, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		response.Error = "Failed to read response body: " + err.Error()
-		return response
-	}
-
-	response.Body = string(bodyBytes)
-
-	return response
 }
 
 // FormatJSON formats a JSON string with indentation
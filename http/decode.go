@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultAcceptEncoding is sent when the request does not set Accept-Encoding
+const defaultAcceptEncoding = "gzip, deflate, br, zstd"
+
+// decodeBody reverses the Content-Encoding of a response body.
+// Encodings are listed in the order they were applied, so they are undone in reverse.
+// Decoded bodies are cut at limit bytes, which truncated reports.
+func decodeBody(body []byte, contentEncoding string, limit int64) (decoded []byte, truncated bool, err error) {
+	// Responses without a body, such as to HEAD requests, keep their encoding header
+	if len(body) == 0 {
+		return body, false, nil
+	}
+
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+
+		decoded, cut, err := decodeOne(body, encoding, limit)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", encoding, err)
+		}
+		body = decoded
+		truncated = truncated || cut
+	}
+	return body, truncated, nil
+}
+
+// decodeOne undoes a single content encoding
+func decodeOne(body []byte, encoding string, limit int64) ([]byte, bool, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, false, err
+		}
+		defer r.Close()
+		return readLimited(r, limit)
+
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but some servers send raw DEFLATE
+		if r, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			defer r.Close()
+			return readLimited(r, limit)
+		}
+		r := flate.NewReader(bytes.NewReader(body))
+		defer r.Close()
+		return readLimited(r, limit)
+
+	case "br":
+		return readLimited(brotli.NewReader(bytes.NewReader(body)), limit)
+
+	case "zstd":
+		r, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, false, err
+		}
+		defer r.Close()
+		return readLimited(r, limit)
+
+	default:
+		return nil, false, fmt.Errorf("unsupported content encoding")
+	}
+}
+
+// readLimited reads r up to limit bytes, so that a small compressed body
+// cannot expand to fill memory, and reports whether there was more
+func readLimited(r io.Reader, limit int64) ([]byte, bool, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > limit {
+		return data[:limit], true, nil
+	}
+	return data, false, nil
+}
@@ -0,0 +1,498 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"percentman/models"
+)
+
+// introspectionQuery fetches the types, fields and arguments of a schema
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types {
+      kind
+      name
+      description
+      fields(includeDeprecated: true) {
+        name
+        description
+        args { name type { ...TypeRef } }
+        type { ...TypeRef }
+      }
+      inputFields { name type { ...TypeRef } }
+      enumValues(includeDeprecated: true) { name }
+    }
+  }
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+// GraphQLSchema is a browsable summary of an introspected schema
+type GraphQLSchema struct {
+	QueryType        string
+	MutationType     string
+	SubscriptionType string
+	Types            map[string]*GraphQLType
+	// TypeNames lists the non-introspection types in alphabetical order
+	TypeNames []string
+}
+
+// GraphQLType describes one named type
+type GraphQLType struct {
+	Name        string
+	Kind        string
+	Description string
+	Fields      []GraphQLField
+	EnumValues  []string
+}
+
+// GraphQLField describes a field or input field of a type
+type GraphQLField struct {
+	Name        string
+	Description string
+	// Type is the full type reference, e.g. "[User!]!"
+	Type string
+	// TypeName is the named type once lists and non-null are unwrapped
+	TypeName string
+	Args     []GraphQLField
+}
+
+// graphQLTypeRef mirrors the introspection TypeRef fragment
+type graphQLTypeRef struct {
+	Kind   string          `json:"kind"`
+	Name   string          `json:"name"`
+	OfType *graphQLTypeRef `json:"ofType"`
+}
+
+// String renders a type reference in SDL notation
+func (t *graphQLTypeRef) String() string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind {
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	default:
+		return t.Name
+	}
+}
+
+// named returns the innermost named type
+func (t *graphQLTypeRef) named() string {
+	for t != nil && t.Name == "" {
+		t = t.OfType
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name
+}
+
+// GraphQLPayload builds the JSON body for a GraphQL request
+func GraphQLPayload(g *models.GraphQLBody) ([]byte, error) {
+	payload := map[string]interface{}{
+		"query": g.Query,
+	}
+
+	variables, err := graphQLVariables(g)
+	if err != nil {
+		return nil, err
+	}
+	if variables != nil {
+		payload["variables"] = variables
+	}
+	if g.OperationName != "" {
+		payload["operationName"] = g.OperationName
+	}
+
+	return json.Marshal(payload)
+}
+
+// graphQLVariables parses the variables JSON, which must be an object if present
+func graphQLVariables(g *models.GraphQLBody) (json.RawMessage, error) {
+	text := strings.TrimSpace(g.Variables)
+	if text == "" {
+		return nil, nil
+	}
+
+	var variables map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &variables); err != nil {
+		return nil, fmt.Errorf("GraphQL variables must be a JSON object: %w", err)
+	}
+	return json.RawMessage(text), nil
+}
+
+// GraphQLQueryString encodes a GraphQL request as URL parameters for GET requests
+func GraphQLQueryString(rawURL string, g *models.GraphQLBody) (string, error) {
+	variables, err := graphQLVariables(g)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("query", g.Query)
+	if variables != nil {
+		params.Set("variables", string(variables))
+	}
+	if g.OperationName != "" {
+		params.Set("operationName", g.OperationName)
+	}
+
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + params.Encode(), nil
+}
+
+// IntrospectGraphQL fetches the schema of the GraphQL endpoint targeted by req
+func (c *Client) IntrospectGraphQL(req *models.Request) (*GraphQLSchema, error) {
+	introspect := req.Clone()
+	introspect.Method = "POST"
+	introspect.Stream = false
+	introspect.BodyMode = models.BodyModeGraphQL
+	introspect.GraphQL = &models.GraphQLBody{
+		Query:         introspectionQuery,
+		OperationName: "IntrospectionQuery",
+	}
+
+	resp := c.SendRequest(introspect)
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("introspection failed: %s", resp.Status)
+	}
+
+	return ParseGraphQLSchema(resp.Body)
+}
+
+// ParseGraphQLSchema parses an introspection query result
+func ParseGraphQLSchema(body string) (*GraphQLSchema, error) {
+	type rawField struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Args        []rawField      `json:"args"`
+		Type        *graphQLTypeRef `json:"type"`
+	}
+	var result struct {
+		Data struct {
+			Schema *struct {
+				QueryType        *struct{ Name string } `json:"queryType"`
+				MutationType     *struct{ Name string } `json:"mutationType"`
+				SubscriptionType *struct{ Name string } `json:"subscriptionType"`
+				Types            []struct {
+					Kind        string     `json:"kind"`
+					Name        string     `json:"name"`
+					Description string     `json:"description"`
+					Fields      []rawField `json:"fields"`
+					InputFields []rawField `json:"inputFields"`
+					EnumValues  []struct {
+						Name string `json:"name"`
+					} `json:"enumValues"`
+				} `json:"types"`
+			} `json:"__schema"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %w", err)
+	}
+	raw := result.Data.Schema
+	if raw == nil {
+		if errs := GraphQLErrors(body); len(errs) > 0 {
+			return nil, fmt.Errorf("introspection failed: %s", errs[0])
+		}
+		return nil, errors.New("introspection response has no schema")
+	}
+
+	convert := func(f rawField) GraphQLField {
+		field := GraphQLField{
+			Name:        f.Name,
+			Description: f.Description,
+			Type:        f.Type.String(),
+			TypeName:    f.Type.named(),
+		}
+		for _, a := range f.Args {
+			field.Args = append(field.Args, GraphQLField{
+				Name:     a.Name,
+				Type:     a.Type.String(),
+				TypeName: a.Type.named(),
+			})
+		}
+		return field
+	}
+
+	schema := &GraphQLSchema{Types: make(map[string]*GraphQLType)}
+	if raw.QueryType != nil {
+		schema.QueryType = raw.QueryType.Name
+	}
+	if raw.MutationType != nil {
+		schema.MutationType = raw.MutationType.Name
+	}
+	if raw.SubscriptionType != nil {
+		schema.SubscriptionType = raw.SubscriptionType.Name
+	}
+
+	for _, t := range raw.Types {
+		typ := &GraphQLType{
+			Name:        t.Name,
+			Kind:        t.Kind,
+			Description: t.Description,
+		}
+		for _, f := range t.Fields {
+			typ.Fields = append(typ.Fields, convert(f))
+		}
+		for _, f := range t.InputFields {
+			typ.Fields = append(typ.Fields, convert(f))
+		}
+		for _, v := range t.EnumValues {
+			typ.EnumValues = append(typ.EnumValues, v.Name)
+		}
+
+		schema.Types[t.Name] = typ
+		if !strings.HasPrefix(t.Name, "__") {
+			schema.TypeNames = append(schema.TypeNames, t.Name)
+		}
+	}
+	sort.Strings(schema.TypeNames)
+
+	return schema, nil
+}
+
+// field returns the named field of a type, or nil
+func (s *GraphQLSchema) field(typeName, fieldName string) *GraphQLField {
+	typ := s.Types[typeName]
+	if typ == nil {
+		return nil
+	}
+	for i := range typ.Fields {
+		if typ.Fields[i].Name == fieldName {
+			return &typ.Fields[i]
+		}
+	}
+	return nil
+}
+
+// gqlToken is a lexical token of a GraphQL document
+type gqlToken struct {
+	text string
+	name bool
+	end  int
+}
+
+// graphQLTokens splits a GraphQL document into names and punctuators,
+// skipping whitespace, commas, comments and string literals
+func graphQLTokens(src string) []gqlToken {
+	var tokens []gqlToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			if end < 0 {
+				return tokens
+			}
+			i += end + 6
+		case c == '"':
+			i++
+			for i < len(src) && src[i] != '"' && src[i] != '\n' {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(src) && (src[i] == '_' || (src[i] >= 'a' && src[i] <= 'z') ||
+				(src[i] >= 'A' && src[i] <= 'Z') || (src[i] >= '0' && src[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, gqlToken{text: src[start:i], name: true, end: i})
+		case strings.HasPrefix(src[i:], "..."):
+			i += 3
+			tokens = append(tokens, gqlToken{text: "...", end: i})
+		default:
+			i++
+			tokens = append(tokens, gqlToken{text: string(c), end: i})
+		}
+	}
+	return tokens
+}
+
+// GraphQLOperationNames returns the names of the operations defined in a document
+func GraphQLOperationNames(query string) []string {
+	var names []string
+	depth := 0
+	tokens := graphQLTokens(query)
+	for i, t := range tokens {
+		switch {
+		case t.text == "{":
+			depth++
+		case t.text == "}":
+			depth--
+		case depth == 0 && t.name && (t.text == "query" || t.text == "mutation" || t.text == "subscription"):
+			if i+1 < len(tokens) && tokens[i+1].name {
+				names = append(names, tokens[i+1].text)
+			}
+		}
+	}
+	return names
+}
+
+// Suggest returns the fields that may be typed at offset (a byte index into
+// query), filtered by the partially typed name before the cursor
+func (s *GraphQLSchema) Suggest(query string, offset int) (prefix string, fields []GraphQLField) {
+	if offset > len(query) {
+		offset = len(query)
+	}
+	tokens := graphQLTokens(query[:offset])
+
+	// A name touching the cursor is the prefix being completed
+	if n := len(tokens); n > 0 && tokens[n-1].name && tokens[n-1].end == offset {
+		prefix = tokens[n-1].text
+		tokens = tokens[:n-1]
+	}
+
+	var stack []string
+	pending := ""  // type of the next selection set
+	parens := 0    // inside arguments or variable definitions
+	fragment := "" // state while reading "fragment Name on Type" or "... on Type"
+
+	for i, t := range tokens {
+		if parens > 0 {
+			switch t.text {
+			case "(":
+				parens++
+			case ")":
+				parens--
+			}
+			continue
+		}
+
+		switch {
+		case t.text == "(":
+			parens++
+		case t.text == "{":
+			next := pending
+			if len(stack) == 0 && next == "" {
+				next = s.QueryType
+			}
+			stack = append(stack, next)
+			pending = ""
+		case t.text == "}":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			pending = ""
+		case t.text == "...":
+			fragment = "spread"
+		case t.text == "@":
+			fragment = "directive"
+		case !t.name:
+			// Other punctuators carry no type information
+		case fragment == "directive":
+			fragment = ""
+		case t.text == "on" && fragment != "":
+			fragment = "on"
+		case fragment == "on":
+			pending = t.text
+			fragment = ""
+		case fragment == "spread":
+			// Named fragment spread
+			fragment = ""
+		case len(stack) == 0:
+			switch t.text {
+			case "query":
+				pending = s.QueryType
+			case "mutation":
+				pending = s.MutationType
+			case "subscription":
+				pending = s.SubscriptionType
+			case "fragment":
+				fragment = "name"
+			}
+		default:
+			// Skip aliases; the field name follows the colon
+			if i+1 < len(tokens) && tokens[i+1].text == ":" {
+				continue
+			}
+			pending = ""
+			if f := s.field(stack[len(stack)-1], t.text); f != nil {
+				pending = f.TypeName
+			}
+		}
+	}
+
+	if parens > 0 || len(stack) == 0 {
+		return prefix, nil
+	}
+
+	typ := s.Types[stack[len(stack)-1]]
+	if typ == nil {
+		return prefix, nil
+	}
+	lower := strings.ToLower(prefix)
+	for _, f := range typ.Fields {
+		if strings.HasPrefix(strings.ToLower(f.Name), lower) {
+			fields = append(fields, f)
+		}
+	}
+	return prefix, fields
+}
+
+// GraphQLErrors returns the entries of a GraphQL response's errors array
+func GraphQLErrors(body string) []string {
+	var result struct {
+		Errors []struct {
+			Message   string        `json:"message"`
+			Path      []interface{} `json:"path"`
+			Locations []struct {
+				Line   int `json:"line"`
+				Column int `json:"column"`
+			} `json:"locations"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil
+	}
+
+	var errs []string
+	for _, e := range result.Errors {
+		text := e.Message
+		if len(e.Path) > 0 {
+			parts := make([]string, len(e.Path))
+			for i, p := range e.Path {
+				parts[i] = fmt.Sprint(p)
+			}
+			text += " (path: " + strings.Join(parts, ".") + ")"
+		}
+		for _, l := range e.Locations {
+			text += fmt.Sprintf(" at %d:%d", l.Line, l.Column)
+		}
+		errs = append(errs, text)
+	}
+	return errs
+}
@@ -0,0 +1,240 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"percentman/models"
+)
+
+// defaultRetry is the reconnect delay used until the server sends a retry field
+const defaultRetry = 3 * time.Second
+
+// StreamHandler receives the progress of an event stream.
+// Callbacks are invoked from the streaming goroutine.
+type StreamHandler struct {
+	// OnConnect is called with the status and headers of each connection
+	OnConnect func(*models.Response)
+	// OnEvent is called for every dispatched event
+	OnEvent func(models.ServerEvent)
+	// OnError is called when a connection drops; the stream then reconnects
+	OnError func(error)
+}
+
+// streamError is a failure that reconnecting will not fix
+type streamError struct {
+	msg string
+}
+
+func (e *streamError) Error() string {
+	return e.msg
+}
+
+// StreamEvents sends req and reads the response as a Server-Sent Events stream.
+// When a connection ends it reconnects with Last-Event-ID after the server's
+// retry delay. It returns nil once ctx is canceled, or an error if the server
+// answers with something other than a 200 event stream.
+func (c *Client) StreamEvents(ctx context.Context, req *models.Request, handler StreamHandler) error {
+	// The total timeout would cut off a long-lived stream
+	settings := req.EffectiveSettings(c.Defaults())
+	settings.Timeout = 0
+	httpClient := c.clientFor(settings)
+
+	state := &streamState{retry: defaultRetry}
+	for _, h := range req.Headers {
+		if h.Enabled && strings.EqualFold(h.Key, "Last-Event-ID") {
+			state.lastID = h.Value
+		}
+	}
+
+	for {
+		err := c.streamOnce(ctx, httpClient, req, settings.DecodedSizeLimit(), state, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var fatal *streamError
+		if errors.As(err, &fatal) {
+			return err
+		}
+		if err == nil {
+			err = errors.New("stream closed by server")
+		}
+		if handler.OnError != nil {
+			handler.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(state.retry):
+		}
+	}
+}
+
+// streamState carries the reconnection state between connections
+type streamState struct {
+	lastID string
+	retry  time.Duration
+}
+
+// streamOnce opens one connection and reads events until it ends. The body of
+// a response other than 200, up to limit bytes, is passed to OnConnect.
+func (c *Client) streamOnce(ctx context.Context, httpClient *http.Client, req *models.Request, limit int64, state *streamState, handler StreamHandler) error {
+	httpReq, err := newHTTPRequest(ctx, req)
+	if err != nil {
+		return &streamError{msg: err.Error()}
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Cache-Control", "no-cache")
+	// Bodies are only decoded once fully read, so ask for an uncompressed stream
+	httpReq.Header.Set("Accept-Encoding", "identity")
+	if state.lastID != "" {
+		httpReq.Header.Set("Last-Event-ID", state.lastID)
+	}
+
+	startTime := time.Now()
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	response := &models.Response{ResponseTime: time.Since(startTime)}
+	copyResponseHead(response, httpResp)
+	if httpResp.StatusCode != http.StatusOK {
+		// Errors are short documents, read whole like any other response
+		body, truncated, err := readLimited(httpResp.Body, limit)
+		if err == nil {
+			response.RawBody = body
+			response.Body = string(body)
+			response.Size = int64(len(body))
+			response.DecodedSize = response.Size
+			response.Truncated = truncated
+		}
+	}
+	if handler.OnConnect != nil {
+		handler.OnConnect(response)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return &streamError{msg: "Server responded with " + httpResp.Status}
+	}
+	mediaType, _, _ := mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		return &streamError{msg: fmt.Sprintf("Response is not an event stream (Content-Type: %s)", httpResp.Header.Get("Content-Type"))}
+	}
+
+	return readEvents(httpResp.Body, state, handler.OnEvent)
+}
+
+// readEvents parses the event stream format and dispatches complete events
+func readEvents(r io.Reader, state *streamState, onEvent func(models.ServerEvent)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	scanner.Split(scanLines)
+
+	var event models.ServerEvent
+	var data []string
+	hasData := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line dispatches the pending event
+		if line == "" {
+			if hasData {
+				event.Data = strings.Join(data, "\n")
+				event.ID = state.lastID
+				event.Timestamp = time.Now()
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				if onEvent != nil {
+					onEvent(event)
+				}
+			}
+			event = models.ServerEvent{}
+			data = nil
+			hasData = false
+			continue
+		}
+
+		// Lines starting with a colon are comments
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				state.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				state.retry = time.Duration(ms) * time.Millisecond
+				event.Retry = state.retry
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// scanLines splits on CRLF, LF or a lone CR as the event stream format allows
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	for i, b := range data {
+		switch b {
+		case '\n':
+			return i + 1, data[:i], nil
+		case '\r':
+			if i+1 < len(data) {
+				if data[i+1] == '\n' {
+					return i + 2, data[:i], nil
+				}
+				return i + 1, data[:i], nil
+			}
+			if atEOF {
+				return i + 1, data[:i], nil
+			}
+			// Need more data to know whether a LF follows
+			return 0, nil, nil
+		}
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// FormatEvent renders an event in the event stream wire format
+func FormatEvent(e models.ServerEvent) string {
+	var sb strings.Builder
+	if e.Event != "" && e.Event != "message" {
+		sb.WriteString("event: " + e.Event + "\n")
+	}
+	if e.ID != "" {
+		sb.WriteString("id: " + e.ID + "\n")
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"percentman/models"
+)
+
+// timingTrace records when each phase of a request started and ended
+type timingTrace struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+// clientTrace returns hooks that fill in the trace. Dials may race each
+// other, so the first start and the last completion are kept.
+func (t *timingTrace) clientTrace() *httptrace.ClientTrace {
+	set := func(field *time.Time, keepFirst bool) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if !keepFirst || field.IsZero() {
+			*field = time.Now()
+		}
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { set(&t.dnsStart, true) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { set(&t.dnsDone, false) },
+		ConnectStart:         func(string, string) { set(&t.connectStart, true) },
+		ConnectDone:          func(string, string, error) { set(&t.connectDone, false) },
+		TLSHandshakeStart:    func() { set(&t.tlsStart, true) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&t.tlsDone, false) },
+		GotConn:              func(httptrace.GotConnInfo) { set(&t.gotConn, false) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wroteRequest, false) },
+		GotFirstResponseByte: func() { set(&t.firstByte, false) },
+	}
+}
+
+// timings converts the trace to phase durations, with end marking the last body byte
+func (t *timingTrace) timings(end time.Time) *models.Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Time spent queued before the first network activity
+	firstActivity := t.gotConn
+	for _, ts := range []time.Time{t.dnsStart, t.connectStart} {
+		if !ts.IsZero() && (firstActivity.IsZero() || ts.Before(firstActivity)) {
+			firstActivity = ts
+		}
+	}
+
+	return &models.Timings{
+		Blocked: between(t.start, firstActivity),
+		DNS:     between(t.dnsStart, t.dnsDone),
+		Connect: between(t.connectStart, t.connectDone),
+		TLS:     between(t.tlsStart, t.tlsDone),
+		Send:    between(t.gotConn, t.wroteRequest),
+		Wait:    between(t.wroteRequest, t.firstByte),
+		Receive: between(t.firstByte, end),
+	}
+}
+
+// between returns the time from a to b, or zero if either is unknown
+func between(a, b time.Time) time.Duration {
+	if a.IsZero() || b.IsZero() || b.Before(a) {
+		return 0
+	}
+	return b.Sub(a)
+}
@@ -0,0 +1,280 @@
+package http
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"percentman/models"
+)
+
+// Message types shown in the WebSocket log
+const (
+	MessageText   = "text"
+	MessageBinary = "binary"
+	MessagePing   = "ping"
+	MessagePong   = "pong"
+	MessageClose  = "close"
+	MessageError  = "error"
+)
+
+// handshakeHeaders are set by the WebSocket dialer and must not be duplicated
+var handshakeHeaders = map[string]bool{
+	"Upgrade":                  true,
+	"Connection":               true,
+	"Sec-Websocket-Key":        true,
+	"Sec-Websocket-Version":    true,
+	"Sec-Websocket-Extensions": true,
+	"Sec-Websocket-Protocol":   true,
+}
+
+// controlTimeout bounds how long writing a control frame may take
+const controlTimeout = 5 * time.Second
+
+// WebSocketConn is an open WebSocket connection
+type WebSocketConn struct {
+	conn      *websocket.Conn
+	writeMu   sync.Mutex
+	onMessage func(models.WebSocketMessage)
+	done      chan struct{}
+}
+
+// DialWebSocket opens a WebSocket connection for req. Messages received from the
+// server, including control frames, are passed to onMessage from a background
+// goroutine until the connection closes.
+func (c *Client) DialWebSocket(ctx context.Context, req *models.Request, onMessage func(models.WebSocketMessage)) (*WebSocketConn, *models.Response, error) {
+	if req.URL == "" {
+		return nil, nil, errors.New("URL is required")
+	}
+
+	settings := req.EffectiveSettings(c.Defaults())
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: settings.ConnectTimeout,
+		Subprotocols:     req.Subprotocols,
+	}
+	if dialer.HandshakeTimeout == 0 {
+		dialer.HandshakeTimeout = settings.Timeout
+	}
+
+	header := http.Header{}
+	for _, h := range req.Headers {
+		if h.Enabled && h.Key != "" && !handshakeHeaders[http.CanonicalHeaderKey(h.Key)] {
+			header.Set(h.Key, h.Value)
+		}
+	}
+
+	startTime := time.Now()
+	conn, httpResp, err := dialer.DialContext(ctx, webSocketURL(req.URL), header)
+
+	var response *models.Response
+	if httpResp != nil {
+		response = &models.Response{ResponseTime: time.Since(startTime)}
+		copyResponseHead(response, httpResp)
+	}
+	if err != nil {
+		if httpResp != nil && httpResp.StatusCode != http.StatusSwitchingProtocols {
+			err = fmt.Errorf("%w (server responded with %s)", err, httpResp.Status)
+		}
+		return nil, response, err
+	}
+
+	ws := &WebSocketConn{
+		conn:      conn,
+		onMessage: onMessage,
+		done:      make(chan struct{}),
+	}
+
+	conn.SetPingHandler(func(data string) error {
+		ws.emit(models.DirectionReceived, MessagePing, data)
+		err := ws.writeControl(websocket.PongMessage, []byte(data))
+		if err == nil {
+			ws.emit(models.DirectionSent, MessagePong, data)
+		}
+		return err
+	})
+	conn.SetPongHandler(func(data string) error {
+		ws.emit(models.DirectionReceived, MessagePong, data)
+		return nil
+	})
+	conn.SetCloseHandler(func(code int, text string) error {
+		ws.emit(models.DirectionReceived, MessageClose, closeText(code, text))
+		// Echo the close frame as the protocol requires
+		ws.writeControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""))
+		return nil
+	})
+
+	go ws.readLoop()
+
+	return ws, response, nil
+}
+
+// webSocketURL maps http(s) URLs to ws(s) and adds ws:// when no scheme is given
+func webSocketURL(url string) string {
+	switch {
+	case strings.HasPrefix(url, "ws://"), strings.HasPrefix(url, "wss://"):
+		return url
+	case strings.HasPrefix(url, "http://"):
+		return "ws://" + strings.TrimPrefix(url, "http://")
+	case strings.HasPrefix(url, "https://"):
+		return "wss://" + strings.TrimPrefix(url, "https://")
+	default:
+		return "ws://" + url
+	}
+}
+
+// readLoop delivers incoming messages until the connection ends
+func (w *WebSocketConn) readLoop() {
+	defer close(w.done)
+	defer w.conn.Close()
+
+	for {
+		messageType, data, err := w.conn.ReadMessage()
+		if err != nil {
+			// Close frames are reported by the close handler
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				w.emit(models.DirectionInfo, MessageError, err.Error())
+			}
+			return
+		}
+
+		switch messageType {
+		case websocket.TextMessage:
+			w.emit(models.DirectionReceived, MessageText, string(data))
+		case websocket.BinaryMessage:
+			w.emit(models.DirectionReceived, MessageBinary, hex.EncodeToString(data))
+		}
+	}
+}
+
+// emit passes a log entry to the message callback
+func (w *WebSocketConn) emit(direction, messageType, data string) {
+	if w.onMessage == nil {
+		return
+	}
+	w.onMessage(models.WebSocketMessage{
+		Direction: direction,
+		Type:      messageType,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
+
+// writeControl writes a control frame, serialised with other writes
+func (w *WebSocketConn) writeControl(messageType int, data []byte) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	return w.conn.WriteControl(messageType, data, time.Now().Add(controlTimeout))
+}
+
+// SendText sends a text message
+func (w *WebSocketConn) SendText(text string) error {
+	w.writeMu.Lock()
+	err := w.conn.WriteMessage(websocket.TextMessage, []byte(text))
+	w.writeMu.Unlock()
+	if err == nil {
+		w.emit(models.DirectionSent, MessageText, text)
+	}
+	return err
+}
+
+// SendBinary sends a binary message
+func (w *WebSocketConn) SendBinary(data []byte) error {
+	w.writeMu.Lock()
+	err := w.conn.WriteMessage(websocket.BinaryMessage, data)
+	w.writeMu.Unlock()
+	if err == nil {
+		w.emit(models.DirectionSent, MessageBinary, hex.EncodeToString(data))
+	}
+	return err
+}
+
+// Ping sends a ping control frame
+func (w *WebSocketConn) Ping(data string) error {
+	err := w.writeControl(websocket.PingMessage, []byte(data))
+	if err == nil {
+		w.emit(models.DirectionSent, MessagePing, data)
+	}
+	return err
+}
+
+// Close starts the closing handshake and waits briefly for the server to answer
+func (w *WebSocketConn) Close(code int, reason string) error {
+	err := w.writeControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+	if err != nil {
+		w.conn.Close()
+		return err
+	}
+	w.emit(models.DirectionSent, MessageClose, closeText(code, reason))
+
+	select {
+	case <-w.done:
+	case <-time.After(controlTimeout):
+		w.conn.Close()
+	}
+	return nil
+}
+
+// Done is closed once the connection has ended
+func (w *WebSocketConn) Done() <-chan struct{} {
+	return w.done
+}
+
+// Subprotocol returns the subprotocol selected by the server
+func (w *WebSocketConn) Subprotocol() string {
+	return w.conn.Subprotocol()
+}
+
+// closeText describes a close code and reason
+func closeText(code int, reason string) string {
+	text := fmt.Sprintf("%d", code)
+	if name := closeCodeName(code); name != "" {
+		text += " " + name
+	}
+	if reason != "" {
+		text += ": " + reason
+	}
+	return text
+}
+
+// closeCodeName returns the registered name of common close codes
+func closeCodeName(code int) string {
+	switch code {
+	case websocket.CloseNormalClosure:
+		return "Normal Closure"
+	case websocket.CloseGoingAway:
+		return "Going Away"
+	case websocket.CloseProtocolError:
+		return "Protocol Error"
+	case websocket.CloseUnsupportedData:
+		return "Unsupported Data"
+	case websocket.CloseNoStatusReceived:
+		return "No Status Received"
+	case websocket.CloseAbnormalClosure:
+		return "Abnormal Closure"
+	case websocket.CloseInvalidFramePayloadData:
+		return "Invalid Payload"
+	case websocket.ClosePolicyViolation:
+		return "Policy Violation"
+	case websocket.CloseMessageTooBig:
+		return "Message Too Big"
+	case websocket.CloseMandatoryExtension:
+		return "Mandatory Extension"
+	case websocket.CloseInternalServerErr:
+		return "Internal Error"
+	case websocket.CloseServiceRestart:
+		return "Service Restart"
+	case websocket.CloseTryAgainLater:
+		return "Try Again Later"
+	default:
+		return ""
+	}
+}
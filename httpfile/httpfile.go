@@ -0,0 +1,272 @@
+// Package httpfile reads and writes .http request files as used by the
+// VS Code REST Client and JetBrains HTTP Client.
+package httpfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	httpclient "percentman/http"
+	"percentman/models"
+)
+
+// methods are the request line prefixes recognised as HTTP methods
+var methods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true,
+	"HEAD": true, "OPTIONS": true, "CONNECT": true, "TRACE": true,
+}
+
+// variablePattern matches "@name = value" definitions
+var variablePattern = regexp.MustCompile(`^@([A-Za-z_][\w.-]*)\s*=\s*(.*)$`)
+
+// referencePattern matches {{name}} references, excluding dynamic ones such as {{$guid}}
+var referencePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w.-]*)\s*\}\}`)
+
+// File is the content of a .http file
+type File struct {
+	// Variables are the file's @name = value definitions, with references
+	// to earlier definitions already substituted
+	Variables map[string]string
+	Templates []models.Template
+}
+
+// Parse reads the ###-separated requests of a .http file. Requests are named by
+// a "# @name" comment, the text after ###, or their method and path. Repeated
+// names get a number, so that each request of a file has a name of its own.
+func Parse(data []byte) (*File, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	f := &File{Variables: make(map[string]string)}
+
+	var block []string
+	blockName := ""
+	used := make(map[string]bool)
+	flush := func() {
+		if t, ok := f.parseBlock(blockName, block); ok {
+			name := t.Name
+			for n := 2; used[t.Name]; n++ {
+				t.Name = fmt.Sprintf("%s (%d)", name, n)
+			}
+			used[t.Name] = true
+			f.Templates = append(f.Templates, t)
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "###") {
+			flush()
+			block = nil
+			blockName = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+		block = append(block, line)
+	}
+	flush()
+
+	if len(f.Templates) == 0 {
+		return nil, errors.New("no requests found")
+	}
+	return f, nil
+}
+
+// parseBlock parses the request between two separators
+func (f *File) parseBlock(name string, lines []string) (models.Template, bool) {
+	req := models.NewRequest()
+	i := 0
+
+	// Variables, comments and blank lines before the request line
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if comment, ok := commentText(line); ok {
+			if n, ok := strings.CutPrefix(comment, "@name"); ok && strings.TrimSpace(n) != "" {
+				name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(n), "="))
+			}
+			continue
+		}
+		if m := variablePattern.FindStringSubmatch(line); m != nil {
+			f.Variables[m[1]] = models.ExpandVariables(strings.TrimSpace(m[2]), f.Variables)
+			continue
+		}
+		break
+	}
+	if i == len(lines) {
+		return models.Template{}, false
+	}
+
+	// Request line: [METHOD] URL [HTTP/version]
+	fields := strings.Fields(lines[i])
+	req.Method = "GET"
+	if methods[strings.ToUpper(fields[0])] {
+		req.Method = strings.ToUpper(fields[0])
+		fields = fields[1:]
+	}
+	if n := len(fields); n > 1 && strings.HasPrefix(fields[n-1], "HTTP/") {
+		fields = fields[:n-1]
+	}
+	req.URL = strings.Join(fields, " ")
+	i++
+
+	// Query continuation lines
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "?") && !strings.HasPrefix(line, "&") {
+			break
+		}
+		req.URL += line
+	}
+
+	// Headers up to the first blank line
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			i++
+			break
+		}
+		if _, ok := commentText(line); ok {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		req.Headers = append(req.Headers, models.Header{
+			Key:     strings.TrimSpace(key),
+			Value:   strings.TrimSpace(value),
+			Enabled: true,
+		})
+	}
+
+	req.Body = body(lines[i:])
+
+	if name == "" {
+		name = req.Method + " " + requestPath(req.URL)
+	}
+	return models.Template{Name: name, Request: *req}, true
+}
+
+// body joins the body lines, dropping response handler scripts and
+// response references, which percentman does not run
+func body(lines []string) string {
+	var kept []string
+	inScript := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inScript:
+			inScript = !strings.Contains(trimmed, "%}")
+			continue
+		case strings.HasPrefix(trimmed, "> {%"):
+			inScript = !strings.Contains(trimmed[4:], "%}")
+			continue
+		case strings.HasPrefix(trimmed, "<> "), strings.HasPrefix(trimmed, "> "):
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// commentText returns the text of a # or // comment line
+func commentText(line string) (string, bool) {
+	if rest, ok := strings.CutPrefix(line, "//"); ok {
+		return strings.TrimSpace(rest), true
+	}
+	if rest, ok := strings.CutPrefix(line, "#"); ok {
+		return strings.TrimSpace(rest), true
+	}
+	return "", false
+}
+
+// requestPath returns the path of a URL for naming, falling back to the URL itself
+func requestPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Path == "" {
+		return raw
+	}
+	return u.Path
+}
+
+// Format writes templates as a .http file. Variables referenced by the
+// templates are defined at the top using the values in vars. WebSocket and
+// gRPC requests have no .http form and are skipped; GraphQL bodies are
+// written as their JSON payload.
+func Format(templates []models.Template, vars map[string]string) ([]byte, int) {
+	var buf bytes.Buffer
+	skipped := 0
+
+	// Define the referenced variables first
+	used := make(map[string]bool)
+	for _, t := range templates {
+		for _, s := range templateStrings(&t.Request) {
+			for _, m := range referencePattern.FindAllStringSubmatch(s, -1) {
+				if _, ok := vars[m[1]]; ok {
+					used[m[1]] = true
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "@%s = %s\n", name, vars[name])
+	}
+	if len(names) > 0 {
+		buf.WriteString("\n")
+	}
+
+	for _, t := range templates {
+		req := t.Request
+		if req.IsWebSocket() || req.IsGRPC() {
+			skipped++
+			continue
+		}
+
+		fmt.Fprintf(&buf, "### %s\n", t.Name)
+		fmt.Fprintf(&buf, "%s %s\n", req.Method, req.URL)
+
+		body := req.Body
+		if req.IsGraphQL() {
+			if payload, err := httpclient.GraphQLPayload(req.GraphQL); err == nil {
+				body = string(payload)
+			}
+		}
+
+		// Disabled headers are kept as comments
+		for _, h := range req.Headers {
+			if h.Key == "" {
+				continue
+			}
+			if h.Enabled {
+				fmt.Fprintf(&buf, "%s: %s\n", h.Key, h.Value)
+			} else {
+				fmt.Fprintf(&buf, "# %s: %s\n", h.Key, h.Value)
+			}
+		}
+		if body != "" {
+			buf.WriteString("\n" + body + "\n")
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), skipped
+}
+
+// templateStrings lists the parts of a request that may reference variables
+func templateStrings(req *models.Request) []string {
+	s := []string{req.URL, req.Body}
+	for _, h := range req.Headers {
+		s = append(s, h.Key, h.Value)
+	}
+	if req.GraphQL != nil {
+		s = append(s, req.GraphQL.Variables)
+	}
+	return s
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"fyne.io/fyne/v2"
@@ -11,6 +12,10 @@ import (
 )
 
 func main() {
+	workspace := flag.String("workspace", "", "data directory to use instead of ~/.gopostman (or $PERCENTMAN_HOME)")
+	backend := flag.String("backend", "", "storage backend, \"json\" or \"bolt\" (default: bolt if the workspace has a database, json otherwise)")
+	flag.Parse()
+
 	// Create Fyne app
 	a := app.New()
 
@@ -19,13 +24,23 @@ func main() {
 	window.Resize(fyne.NewSize(1200, 800))
 
 	// Initialize storage
-	store, err := storage.NewStorage()
+	store, err := storage.Open(*workspace, *backend)
 	if err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 
+	vault, err := storage.OpenVault(store.Dir())
+	if err != nil {
+		log.Fatalf("Failed to open secrets: %v", err)
+	}
+
 	// Create and build UI
-	application := ui.NewApp(a, window, store)
+	application := ui.NewApp(a, window, store, vault)
+	// The workspace may have been switched since
+	defer func() {
+		application.SaveSession()
+		application.GetStorage().Close()
+	}()
 	content := application.BuildUI()
 
 	window.SetContent(content)
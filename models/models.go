@@ -1,20 +1,125 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Header represents a key-value pair for HTTP headers
 type Header struct {
-	Key     string `json:"key"`
-	Value   string `json:"value"`
-	Enabled bool   `json:"enabled"`
+	Key     string `json:"key" yaml:"key"`
+	Value   string `json:"value" yaml:"value"`
+	Enabled bool   `json:"enabled" yaml:"enabled"`
 }
 
+// Request types
+const (
+	RequestTypeHTTP      = "http"
+	RequestTypeWebSocket = "websocket"
+	RequestTypeGRPC      = "grpc"
+)
+
 // Request represents an HTTP request configuration
 type Request struct {
-	Method  string   `json:"method"`
-	URL     string   `json:"url"`
-	Headers []Header `json:"headers"`
-	Body    string   `json:"body"`
+	// Type is one of the RequestType constants; empty means HTTP
+	Type     string           `json:"type,omitempty" yaml:"type,omitempty"`
+	Method   string           `json:"method" yaml:"method"`
+	URL      string           `json:"url" yaml:"url"`
+	Headers  []Header         `json:"headers" yaml:"headers,omitempty"`
+	Body     string           `json:"body" yaml:"body,omitempty"`
+	Settings *RequestSettings `json:"settings,omitempty" yaml:"settings,omitempty"`
+	// Stream reads the response as a Server-Sent Events stream
+	Stream bool `json:"stream,omitempty" yaml:"stream,omitempty"`
+	// Subprotocols are offered during a WebSocket handshake
+	Subprotocols []string `json:"subprotocols,omitempty" yaml:"subprotocols,omitempty"`
+	// BodyMode is one of the BodyMode constants; empty means raw
+	BodyMode string       `json:"body_mode,omitempty" yaml:"body_mode,omitempty"`
+	GraphQL  *GraphQLBody `json:"graphql,omitempty" yaml:"graphql,omitempty"`
+	// GRPC describes the method to call for gRPC requests; Body holds the JSON message
+	GRPC *GRPCRequest `json:"grpc,omitempty" yaml:"grpc,omitempty"`
+}
+
+// GRPCRequest identifies a gRPC method and how to discover it
+type GRPCRequest struct {
+	Service string `json:"service" yaml:"service"`
+	Method  string `json:"method" yaml:"method"`
+	// ProtoFiles are parsed instead of using server reflection when set
+	ProtoFiles  []string `json:"proto_files,omitempty" yaml:"proto_files,omitempty"`
+	ImportPaths []string `json:"import_paths,omitempty" yaml:"import_paths,omitempty"`
+	Plaintext   bool     `json:"plaintext" yaml:"plaintext"`
+}
+
+// Body modes
+const (
+	BodyModeRaw     = "raw"
+	BodyModeGraphQL = "graphql"
+)
+
+// GraphQLBody holds the parts of a GraphQL request body
+type GraphQLBody struct {
+	Query string `json:"query" yaml:"query"`
+	// Variables is kept as the JSON text the user typed
+	Variables     string `json:"variables,omitempty" yaml:"variables,omitempty"`
+	OperationName string `json:"operation_name,omitempty" yaml:"operation_name,omitempty"`
+}
+
+// RequestSettings holds the transport options used to send a request.
+// A zero timeout means no limit.
+type RequestSettings struct {
+	Timeout               time.Duration `json:"timeout" yaml:"timeout"`
+	ConnectTimeout        time.Duration `json:"connect_timeout" yaml:"connect_timeout"`
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout" yaml:"response_header_timeout"`
+	DisableKeepAlive      bool          `json:"disable_keep_alive" yaml:"disable_keep_alive"`
+	DisableHTTP2          bool          `json:"disable_http2" yaml:"disable_http2"`
+	// MaxDecodedSize limits decompressed response bodies, in bytes; 0 uses DefaultMaxDecodedSize
+	MaxDecodedSize int64 `json:"max_decoded_size,omitempty" yaml:"max_decoded_size,omitempty"`
+}
+
+// DefaultMaxDecodedSize limits decompressed bodies unless settings say otherwise
+const DefaultMaxDecodedSize = 100 << 20
+
+// DecodedSizeLimit returns the limit for decompressed response bodies
+func (s RequestSettings) DecodedSizeLimit() int64 {
+	if s.MaxDecodedSize > 0 {
+		return s.MaxDecodedSize
+	}
+	return DefaultMaxDecodedSize
+}
+
+// Settings represents app-wide preferences
+type Settings struct {
+	// Defaults apply to every request that has no settings of its own
+	Defaults RequestSettings `json:"defaults"`
+	// Variables replace {{name}} placeholders when requests are sent
+	Variables map[string]string `json:"variables,omitempty"`
+	// Spec is the path of the OpenAPI document responses are validated against
+	Spec string `json:"spec,omitempty"`
+	// Collection is a directory holding one file per template, for keeping
+	// templates in version control; empty keeps them in templates.json
+	Collection string `json:"collection,omitempty"`
+	// History limits how much request history is kept
+	History HistoryRetention `json:"history"`
+	// Redaction selects the values masked in history and exports
+	Redaction RedactionPolicy `json:"redaction"`
+}
+
+// RedactionPolicy selects sensitive values, such as credentials, to mask
+type RedactionPolicy struct {
+	// Headers are header names, ignoring case
+	Headers []string `json:"headers"`
+	// Fields are JSON body field paths: "password" selects the field at any
+	// depth, "user.*.token" selects from the root with * matching any key or index
+	Fields []string `json:"fields"`
+	// Query are URL query parameter names, ignoring case
+	Query []string `json:"query"`
+}
+
+// HistoryRetention limits the request history. The oldest items are removed first.
+type HistoryRetention struct {
+	// MaxItems is how many items are kept; 0 keeps any number
+	MaxItems int `json:"max_items"`
+	// MaxAgeDays removes items older than this many days; 0 keeps them forever
+	MaxAgeDays int `json:"max_age_days"`
 }
 
 // Response represents an HTTP response
@@ -25,6 +130,90 @@ type Response struct {
 	Body         string            `json:"body"`
 	ResponseTime time.Duration     `json:"response_time"`
 	Error        string            `json:"error,omitempty"`
+	// ErrorKind is one of the ErrorKind constants when the request failed
+	ErrorKind string `json:"error_kind,omitempty"`
+
+	// RawBody holds the body bytes as received, before any decoding
+	RawBody         []byte `json:"-"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+	Size            int64  `json:"size"`
+	DecodedSize     int64  `json:"decoded_size"`
+	// DecodeError is set when the body could not be decompressed; Body then holds the raw bytes
+	DecodeError string `json:"decode_error,omitempty"`
+	// Truncated is set when the decompressed body was cut at the size limit
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Trailers holds gRPC trailing metadata
+	Trailers map[string]string `json:"trailers,omitempty"`
+
+	// Proto is the protocol version, such as "HTTP/1.1"
+	Proto   string   `json:"proto,omitempty"`
+	Timings *Timings `json:"timings,omitempty"`
+}
+
+// JoinHeader joins the values of a repeated response header. Set-Cookie values
+// contain commas of their own, so they are kept on separate lines instead.
+func JoinHeader(name string, values []string) string {
+	if strings.EqualFold(name, "Set-Cookie") {
+		return strings.Join(values, "\n")
+	}
+	return strings.Join(values, ", ")
+}
+
+// SplitHeader returns the values of a response header joined by JoinHeader
+// that have to be sent separately
+func SplitHeader(name, value string) []string {
+	if strings.EqualFold(name, "Set-Cookie") {
+		return strings.Split(value, "\n")
+	}
+	return []string{value}
+}
+
+// Error kinds of requests that got no response
+const (
+	ErrorKindDNS      = "dns"
+	ErrorKindConnect  = "connect"
+	ErrorKindTLS      = "tls"
+	ErrorKindTimeout  = "timeout"
+	ErrorKindCanceled = "canceled"
+	// ErrorKindOther covers invalid requests and failures while reading a response
+	ErrorKindOther = "other"
+)
+
+// Timings breaks the duration of a request into phases, as HAR does.
+// Phases that did not happen, such as DNS on a reused connection, are zero.
+type Timings struct {
+	Blocked time.Duration `json:"blocked"`
+	DNS     time.Duration `json:"dns"`
+	Connect time.Duration `json:"connect"`
+	TLS     time.Duration `json:"tls"`
+	Send    time.Duration `json:"send"`
+	Wait    time.Duration `json:"wait"`
+	Receive time.Duration `json:"receive"`
+}
+
+// ServerEvent represents one Server-Sent Events frame
+type ServerEvent struct {
+	Event     string        `json:"event,omitempty"`
+	ID        string        `json:"id,omitempty"`
+	Data      string        `json:"data"`
+	Retry     time.Duration `json:"retry,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// WebSocket message directions
+const (
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+	DirectionInfo     = "info"
+)
+
+// WebSocketMessage represents one entry in a WebSocket session log
+type WebSocketMessage struct {
+	Direction string    `json:"direction"`
+	Type      string    `json:"type"`
+	Data      string    `json:"data"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // Template represents a saved request template
@@ -34,6 +223,22 @@ type Template struct {
 	Request   Request   `json:"request"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Folder groups templates in the sidebar; empty means top level
+	Folder string `json:"folder,omitempty"`
+	// Source identifies where an imported template came from, so re-imports update it
+	Source string `json:"source,omitempty"`
+	// Revision counts the saves of this template, so concurrent edits can be merged
+	Revision int `json:"revision,omitempty"`
+}
+
+// TemplateRevision is a version of a template that was saved over
+type TemplateRevision struct {
+	Revision int     `json:"revision"`
+	Name     string  `json:"name"`
+	Request  Request `json:"request"`
+	// SavedAt is when this version was saved
+	SavedAt time.Time `json:"saved_at"`
 }
 
 // HistoryItem represents a request history entry
@@ -42,6 +247,21 @@ type HistoryItem struct {
 	Request   Request   `json:"request"`
 	Response  Response  `json:"response"`
 	Timestamp time.Time `json:"timestamp"`
+	// Redacted is set when values were masked before the item was stored
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// Session records the requests open in the editor, so they can be restored
+type Session struct {
+	Tabs []SessionTab `json:"tabs"`
+	// Active is the index of the selected tab
+	Active int `json:"active"`
+}
+
+// SessionTab is an open request and the template it was loaded from, if any
+type SessionTab struct {
+	TemplateID string  `json:"template_id,omitempty"`
+	Request    Request `json:"request"`
 }
 
 // NewRequest creates a new request with default values
@@ -54,14 +274,165 @@ func NewRequest() *Request {
 	}
 }
 
+// DefaultRequestSettings returns the built-in transport settings
+func DefaultRequestSettings() RequestSettings {
+	return RequestSettings{
+		Timeout:        30 * time.Second,
+		ConnectTimeout: 10 * time.Second,
+	}
+}
+
+// DefaultSettings returns the app-wide preferences used before any are saved
+func DefaultSettings() Settings {
+	return Settings{
+		Defaults: DefaultRequestSettings(),
+		History:  HistoryRetention{MaxItems: 1000},
+		Redaction: RedactionPolicy{
+			Headers: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"},
+			Fields:  []string{"password", "token", "access_token", "refresh_token", "client_secret"},
+			Query:   []string{"api_key", "apikey", "access_token", "token"},
+		},
+	}
+}
+
 // Clone creates a copy of the request
 func (r *Request) Clone() *Request {
 	headers := make([]Header, len(r.Headers))
 	copy(headers, r.Headers)
+
+	var settings *RequestSettings
+	if r.Settings != nil {
+		s := *r.Settings
+		settings = &s
+	}
+
+	var graphQL *GraphQLBody
+	if r.GraphQL != nil {
+		g := *r.GraphQL
+		graphQL = &g
+	}
+
+	var grpc *GRPCRequest
+	if r.GRPC != nil {
+		g := *r.GRPC
+		g.ProtoFiles = append([]string(nil), r.GRPC.ProtoFiles...)
+		g.ImportPaths = append([]string(nil), r.GRPC.ImportPaths...)
+		grpc = &g
+	}
+
 	return &Request{
-		Method:  r.Method,
-		URL:     r.URL,
-		Headers: headers,
-		Body:    r.Body,
+		Type:         r.Type,
+		Method:       r.Method,
+		URL:          r.URL,
+		Headers:      headers,
+		Body:         r.Body,
+		Settings:     settings,
+		Stream:       r.Stream,
+		Subprotocols: append([]string(nil), r.Subprotocols...),
+		BodyMode:     r.BodyMode,
+		GraphQL:      graphQL,
+		GRPC:         grpc,
+	}
+}
+
+// IsWebSocket reports whether the request is a WebSocket session
+func (r *Request) IsWebSocket() bool {
+	return r.Type == RequestTypeWebSocket
+}
+
+// IsGRPC reports whether the request is a gRPC call
+func (r *Request) IsGRPC() bool {
+	return r.Type == RequestTypeGRPC
+}
+
+// IsGraphQL reports whether the body is sent as a GraphQL request
+func (r *Request) IsGraphQL() bool {
+	return r.BodyMode == BodyModeGraphQL && r.GraphQL != nil
+}
+
+// EffectiveSettings returns the request's own settings, or defaults if it has none
+func (r *Request) EffectiveSettings(defaults RequestSettings) RequestSettings {
+	if r.Settings != nil {
+		return *r.Settings
+	}
+	return defaults
+}
+
+// SecretPrefix starts placeholders that refer to vault secrets, as in {{secret:token}}
+const SecretPrefix = "secret:"
+
+// UsesSecrets reports whether the request refers to vault secrets
+func (r *Request) UsesSecrets() bool {
+	fields := []string{r.URL, r.Body}
+	for _, h := range r.Headers {
+		fields = append(fields, h.Key, h.Value)
+	}
+	if r.GraphQL != nil {
+		fields = append(fields, r.GraphQL.Variables)
+	}
+	for _, f := range fields {
+		if RefersToSecret(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// RefersToSecret reports whether s contains a {{secret:name}} placeholder
+func RefersToSecret(s string) bool {
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			return false
+		}
+		s = s[start+2:]
+		if strings.HasPrefix(strings.TrimSpace(s), SecretPrefix) {
+			return true
+		}
+	}
+}
+
+// ExpandVariables replaces {{name}} placeholders with their values.
+// Unknown placeholders are left as they are.
+func ExpandVariables(s string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(s, "{{") {
+		return s
+	}
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start
+		name := strings.TrimSpace(s[start+2 : end])
+		b.WriteString(s[:start])
+		if value, ok := vars[name]; ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(s[start : end+2])
+		}
+		s = s[end+2:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// Expand returns a copy of the request with variables substituted in its URL, headers and body
+func (r *Request) Expand(vars map[string]string) *Request {
+	req := r.Clone()
+	req.URL = ExpandVariables(req.URL, vars)
+	for i, h := range req.Headers {
+		req.Headers[i].Key = ExpandVariables(h.Key, vars)
+		req.Headers[i].Value = ExpandVariables(h.Value, vars)
+	}
+	req.Body = ExpandVariables(req.Body, vars)
+	if req.GraphQL != nil {
+		req.GraphQL.Variables = ExpandVariables(req.GraphQL.Variables, vars)
 	}
+	return req
 }
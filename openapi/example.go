@@ -0,0 +1,210 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxExampleDepth limits how deeply nested schemas are expanded
+const maxExampleDepth = 8
+
+// example synthesises a value matching schema. seen holds the references being
+// expanded, so recursive schemas stop instead of looping.
+func (s *Document) example(v any, depth int, seen map[string]bool) any {
+	schema := mapValue(v)
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if seen[ref] {
+			return nil
+		}
+		seen[ref] = true
+		defer delete(seen, ref)
+		return s.example(s.lookup(ref), depth, seen)
+	}
+
+	for _, key := range []string{"example", "const", "default"} {
+		if value, ok := schema[key]; ok {
+			return value
+		}
+	}
+	if examples := listValue(schema["examples"]); len(examples) > 0 {
+		return examples[0]
+	}
+	if enum := listValue(schema["enum"]); len(enum) > 0 {
+		return enum[0]
+	}
+
+	// Compositions: allOf merges objects, oneOf and anyOf use their first option
+	if all := listValue(schema["allOf"]); len(all) > 0 {
+		merged := make(map[string]any)
+		var other any
+		for _, part := range all {
+			switch value := s.example(part, depth+1, seen).(type) {
+			case map[string]any:
+				for k, item := range value {
+					merged[k] = item
+				}
+			case nil:
+			default:
+				other = value
+			}
+		}
+		if len(merged) == 0 && other != nil {
+			return other
+		}
+		if props := mapValue(schema["properties"]); len(props) > 0 {
+			for k, item := range s.objectExample(schema, depth, seen) {
+				merged[k] = item
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if options := listValue(schema[key]); len(options) > 0 {
+			return s.example(options[0], depth+1, seen)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		return s.objectExample(schema, depth, seen)
+	case "array":
+		item := s.example(schema["items"], depth+1, seen)
+		if item == nil {
+			return []any{}
+		}
+		return []any{item}
+	case "string":
+		return stringExample(stringValue(schema["format"]))
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	default:
+		return nil
+	}
+}
+
+// objectExample fills every property of an object schema
+func (s *Document) objectExample(schema map[string]any, depth int, seen map[string]bool) map[string]any {
+	result := make(map[string]any)
+	props := mapValue(schema["properties"])
+	for name, prop := range props {
+		// Read-only properties are set by the server
+		if readOnly, _ := s.resolve(prop)["readOnly"].(bool); readOnly {
+			continue
+		}
+		result[name] = s.example(prop, depth+1, seen)
+	}
+	if len(props) == 0 {
+		if additional := mapValue(schema["additionalProperties"]); additional != nil {
+			result["key"] = s.example(additional, depth+1, seen)
+		}
+	}
+	return result
+}
+
+// schemaType returns the type of a schema, inferring objects from their properties.
+// OpenAPI 3.1 allows a list of types; the first non-null one is used.
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, item := range t {
+			if name := stringValue(item); name != "null" {
+				return name
+			}
+		}
+	}
+	if schema["properties"] != nil || schema["additionalProperties"] != nil {
+		return "object"
+	}
+	if schema["items"] != nil {
+		return "array"
+	}
+	return ""
+}
+
+// stringExample returns a placeholder for a string format
+func stringExample(format string) string {
+	switch format {
+	case "date":
+		return "2024-01-01"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "127.0.0.1"
+	case "ipv6":
+		return "::1"
+	case "byte", "binary":
+		return ""
+	default:
+		return "string"
+	}
+}
+
+// formatBody renders an example value for a content type
+func formatBody(contentType string, value any) string {
+	if value == nil {
+		return ""
+	}
+	switch {
+	case contentType == "application/json" || strings.HasSuffix(contentType, "+json"):
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(value); err != nil {
+			return ""
+		}
+		return strings.TrimSuffix(buf.String(), "\n")
+	case contentType == "application/x-www-form-urlencoded":
+		form := url.Values{}
+		for k, v := range mapValue(value) {
+			form.Set(k, scalarText(v))
+		}
+		return form.Encode()
+	default:
+		return scalarText(value)
+	}
+}
+
+// scalarText renders a value as plain text; lists are comma-separated and objects JSON
+func scalarText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = scalarText(item)
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,438 @@
+// Package openapi turns OpenAPI 3.x and Swagger 2.0 documents into request templates.
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"percentman/models"
+)
+
+// BaseURLVariable is the placeholder imported URLs start with
+const BaseURLVariable = "baseUrl"
+
+// methods lists the operation keys of a path item in display order
+var methods = []string{"get", "post", "put", "patch", "delete", "head", "options", "trace"}
+
+// Result is the outcome of importing a document
+type Result struct {
+	Title string
+	// BaseURL is the first server URL, used as the value of {{baseUrl}}
+	BaseURL   string
+	Templates []models.Template
+}
+
+// Document is a parsed OpenAPI or Swagger document
+type Document struct {
+	root    map[string]any
+	swagger bool
+	title   string
+}
+
+// ParseFile parses the document at path
+func ParseFile(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Import generates one template per operation of a JSON or YAML document
+func Import(data []byte) (*Result, error) {
+	s, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return s.Import()
+}
+
+// Title returns the title of the document
+func (s *Document) Title() string {
+	return s.title
+}
+
+// Import generates one template per operation
+func (s *Document) Import() (*Result, error) {
+	result := &Result{
+		Title:   s.title,
+		BaseURL: s.baseURL(),
+	}
+
+	paths := mapValue(s.root["paths"])
+	for _, path := range sortedKeys(paths) {
+		item := s.resolve(paths[path])
+		for _, method := range methods {
+			op := mapValue(item[method])
+			if op == nil {
+				continue
+			}
+			result.Templates = append(result.Templates, s.template(path, method, item, op))
+		}
+	}
+	if len(result.Templates) == 0 {
+		return nil, errors.New("the document defines no operations")
+	}
+	return result, nil
+}
+
+// Parse decodes a JSON or YAML document and checks its version
+func Parse(data []byte) (*Document, error) {
+	// YAML is a superset of JSON, so one decoder handles both
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	root := mapValue(normalize(doc))
+	if root == nil {
+		return nil, errors.New("invalid document: expected an object at the top level")
+	}
+
+	s := &Document{root: root}
+	switch {
+	case strings.HasPrefix(stringValue(root["openapi"]), "3."):
+	case stringValue(root["swagger"]) == "2.0":
+		s.swagger = true
+	default:
+		return nil, errors.New("not an OpenAPI 3.x or Swagger 2.0 document")
+	}
+
+	s.title = stringValue(mapValue(root["info"])["title"])
+	if s.title == "" {
+		s.title = "API"
+	}
+	return s, nil
+}
+
+// normalize converts YAML maps with non-string keys, such as response codes, to string keys
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = normalize(item)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = normalize(item)
+		}
+		return m
+	case []any:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+		return v
+	case time.Time:
+		// Unquoted dates are decoded as timestamps; keep them as the text they were
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// baseURL returns the first server URL without a trailing slash
+func (s *Document) baseURL() string {
+	if s.swagger {
+		host := stringValue(s.root["host"])
+		basePath := stringValue(s.root["basePath"])
+		if host == "" {
+			return strings.TrimSuffix(basePath, "/")
+		}
+		scheme := "https"
+		if schemes := listValue(s.root["schemes"]); len(schemes) > 0 {
+			scheme = stringValue(schemes[0])
+		}
+		return strings.TrimSuffix(scheme+"://"+host+basePath, "/")
+	}
+
+	servers := listValue(s.root["servers"])
+	if len(servers) == 0 {
+		return ""
+	}
+	server := mapValue(servers[0])
+	u := stringValue(server["url"])
+	// Server variables take their default values
+	vars := mapValue(server["variables"])
+	for name, v := range vars {
+		u = strings.ReplaceAll(u, "{"+name+"}", stringValue(mapValue(v)["default"]))
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+// resolve follows a local $ref, returning the referenced object
+func (s *Document) resolve(v any) map[string]any {
+	m := mapValue(v)
+	for i := 0; i < 32 && m != nil; i++ {
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return m
+		}
+		m = mapValue(s.lookup(ref))
+	}
+	return m
+}
+
+// lookup finds the value a local JSON pointer refers to; external references are not supported
+func (s *Document) lookup(ref string) any {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var v any = s.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		v = mapValue(v)[part]
+		if v == nil {
+			return nil
+		}
+	}
+	return v
+}
+
+// template builds the template of one operation
+func (s *Document) template(path, method string, item, op map[string]any) models.Template {
+	method = strings.ToUpper(method)
+
+	name := stringValue(op["summary"])
+	if name == "" {
+		name = stringValue(op["operationId"])
+	}
+	if name == "" {
+		name = method + " " + path
+	}
+
+	folder := ""
+	if tags := listValue(op["tags"]); len(tags) > 0 {
+		folder = stringValue(tags[0])
+	}
+
+	req := models.NewRequest()
+	req.Method = method
+
+	params := s.parameters(item, op)
+	req.URL = "{{" + BaseURLVariable + "}}" + pathTemplate(path) + s.queryString(params)
+
+	for _, p := range params {
+		if stringValue(p["in"]) != "header" {
+			continue
+		}
+		key := stringValue(p["name"])
+		switch strings.ToLower(key) {
+		case "accept", "content-type", "authorization":
+			// Set by the body and security schemes rather than as parameters
+			continue
+		}
+		value, hasExample := s.parameterValue(p)
+		required, _ := p["required"].(bool)
+		if required || hasExample {
+			req.Headers = append(req.Headers, models.Header{Key: key, Value: value, Enabled: required})
+		}
+	}
+
+	if contentType, body := s.requestBody(op, params); contentType != "" {
+		req.Headers = append(req.Headers, models.Header{Key: "Content-Type", Value: contentType, Enabled: true})
+		req.Body = body
+	}
+
+	return models.Template{
+		Name:    name,
+		Request: *req,
+		Folder:  folder,
+		Source:  "openapi:" + s.title + ":" + method + " " + path,
+	}
+}
+
+// parameters merges path-level and operation-level parameters; the operation wins
+func (s *Document) parameters(item, op map[string]any) []map[string]any {
+	var params []map[string]any
+	index := make(map[string]int)
+	for _, list := range [][]any{listValue(item["parameters"]), listValue(op["parameters"])} {
+		for _, v := range list {
+			p := s.resolve(v)
+			if p == nil {
+				continue
+			}
+			key := stringValue(p["in"]) + ":" + stringValue(p["name"])
+			if i, ok := index[key]; ok {
+				params[i] = p
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// pathTemplate turns {param} path segments into {{param}} placeholders
+func pathTemplate(path string) string {
+	return strings.NewReplacer("{", "{{", "}", "}}").Replace(path)
+}
+
+// queryString lists required query parameters and optional ones that have an example
+func (s *Document) queryString(params []map[string]any) string {
+	var parts []string
+	for _, p := range params {
+		if stringValue(p["in"]) != "query" {
+			continue
+		}
+		value, hasExample := s.parameterValue(p)
+		required, _ := p["required"].(bool)
+		if !required && !hasExample {
+			continue
+		}
+		parts = append(parts, url.QueryEscape(stringValue(p["name"]))+"="+queryEscape(value))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "?" + strings.Join(parts, "&")
+}
+
+// queryEscape escapes a query value but keeps {{variable}} placeholders readable
+func queryEscape(value string) string {
+	if strings.HasPrefix(value, "{{") && strings.HasSuffix(value, "}}") {
+		return value
+	}
+	return url.QueryEscape(value)
+}
+
+// parameterValue returns the example value of a parameter and whether the document gives one
+func (s *Document) parameterValue(p map[string]any) (string, bool) {
+	if v, ok := p["example"]; ok {
+		return scalarText(v), true
+	}
+	if examples := mapValue(p["examples"]); len(examples) > 0 {
+		first := s.resolve(examples[sortedKeys(examples)[0]])
+		if v, ok := first["value"]; ok {
+			return scalarText(v), true
+		}
+	}
+
+	// Swagger 2 describes non-body parameters inline rather than with a schema
+	schema := s.resolve(p["schema"])
+	if s.swagger {
+		schema = p
+	}
+	if schema == nil {
+		return "", false
+	}
+	for _, key := range []string{"example", "default"} {
+		if v, ok := schema[key]; ok {
+			return scalarText(v), true
+		}
+	}
+	if enum := listValue(schema["enum"]); len(enum) > 0 {
+		return scalarText(enum[0]), true
+	}
+	return "", false
+}
+
+// requestBody returns the content type and example body of an operation
+func (s *Document) requestBody(op map[string]any, params []map[string]any) (string, string) {
+	if s.swagger {
+		return s.swaggerBody(op, params)
+	}
+
+	body := s.resolve(op["requestBody"])
+	content := mapValue(body["content"])
+	if len(content) == 0 {
+		return "", ""
+	}
+
+	contentType := preferredMediaType(sortedKeys(content))
+	media := mapValue(content[contentType])
+
+	var example any
+	if v, ok := media["example"]; ok {
+		example = v
+	} else if examples := mapValue(media["examples"]); len(examples) > 0 {
+		example = s.resolve(examples[sortedKeys(examples)[0]])["value"]
+	} else {
+		example = s.example(media["schema"], 0, map[string]bool{})
+	}
+	return contentType, formatBody(contentType, example)
+}
+
+// swaggerBody builds the body of a Swagger 2 operation from its body or formData parameters
+func (s *Document) swaggerBody(op map[string]any, params []map[string]any) (string, string) {
+	consumes := listValue(op["consumes"])
+	if len(consumes) == 0 {
+		consumes = listValue(s.root["consumes"])
+	}
+	types := make([]string, len(consumes))
+	for i, c := range consumes {
+		types[i] = stringValue(c)
+	}
+
+	form := make(map[string]any)
+	for _, p := range params {
+		switch stringValue(p["in"]) {
+		case "body":
+			contentType := preferredMediaType(types)
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			example, ok := p["x-example"]
+			if !ok {
+				example = s.example(p["schema"], 0, map[string]bool{})
+			}
+			return contentType, formatBody(contentType, example)
+		case "formData":
+			value, _ := s.parameterValue(p)
+			form[stringValue(p["name"])] = value
+		}
+	}
+	if len(form) == 0 {
+		return "", ""
+	}
+	return "application/x-www-form-urlencoded", formatBody("application/x-www-form-urlencoded", form)
+}
+
+// preferredMediaType picks JSON when offered, otherwise the first type
+func preferredMediaType(types []string) string {
+	for _, t := range types {
+		if t == "application/json" || strings.HasSuffix(t, "+json") {
+			return t
+		}
+	}
+	if len(types) == 0 {
+		return ""
+	}
+	return types[0]
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func mapValue(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func listValue(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return s
+}
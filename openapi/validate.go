@@ -0,0 +1,519 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"percentman/models"
+)
+
+// Violation is one way a response differs from the document
+type Violation struct {
+	// Pointer locates the offending value in the body as a JSON pointer; empty for the response itself
+	Pointer string
+	Message string
+}
+
+// Validation is the outcome of checking a response against the document
+type Validation struct {
+	// Operation is the matched operation, such as "GET /pets/{petId}"; empty when none matched
+	Operation  string
+	Violations []Violation
+}
+
+// uuidPattern matches the textual form of a UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Validate checks the status code, content type and JSON body of resp against
+// the operation matching req's method and URL
+func (d *Document) Validate(req *models.Request, resp *models.Response) *Validation {
+	path, op := d.findOperation(req.Method, req.URL)
+	if op == nil {
+		return &Validation{}
+	}
+	v := &Validation{Operation: strings.ToUpper(req.Method) + " " + path}
+
+	responses := mapValue(op["responses"])
+	declared := responses[strconv.Itoa(resp.StatusCode)]
+	if declared == nil {
+		declared = responses[fmt.Sprintf("%dXX", resp.StatusCode/100)]
+	}
+	if declared == nil {
+		declared = responses[fmt.Sprintf("%dxx", resp.StatusCode/100)]
+	}
+	if declared == nil {
+		declared = responses["default"]
+	}
+	if declared == nil {
+		v.add("", fmt.Sprintf("status %d is not declared for this operation", resp.StatusCode))
+		return v
+	}
+	response := d.resolve(declared)
+
+	contentType := headerValue(resp.Headers, "Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var schema any
+	if d.swagger {
+		produces := listValue(op["produces"])
+		if len(produces) == 0 {
+			produces = listValue(d.root["produces"])
+		}
+		var types []string
+		for _, p := range produces {
+			types = append(types, stringValue(p))
+		}
+		schema = response["schema"]
+		if schema != nil && len(types) > 0 && matchMediaType(mediaType, types) == "" {
+			v.add("", fmt.Sprintf("content type %q is not declared (expected %s)", contentType, strings.Join(types, ", ")))
+			return v
+		}
+	} else {
+		content := mapValue(response["content"])
+		if len(content) == 0 {
+			return v
+		}
+		types := sortedKeys(content)
+		key := matchMediaType(mediaType, types)
+		if key == "" {
+			v.add("", fmt.Sprintf("content type %q is not declared (expected %s)", contentType, strings.Join(types, ", ")))
+			return v
+		}
+		schema = mapValue(content[key])["schema"]
+	}
+
+	if schema == nil || !isJSONMediaType(mediaType) {
+		return v
+	}
+
+	dec := json.NewDecoder(strings.NewReader(resp.Body))
+	dec.UseNumber()
+	var body any
+	if err := dec.Decode(&body); err != nil {
+		v.add("", "body is not valid JSON: "+err.Error())
+		return v
+	}
+	d.check(schema, body, "", v)
+	return v
+}
+
+// Valid reports whether no violations were found
+func (v *Validation) Valid() bool {
+	return len(v.Violations) == 0
+}
+
+func (v *Validation) add(pointer, message string) {
+	v.Violations = append(v.Violations, Violation{Pointer: pointer, Message: message})
+}
+
+// findOperation matches a method and URL to a path template. Server base paths are
+// stripped first; templates with more literal segments win over more general ones.
+func (d *Document) findOperation(method, rawURL string) (string, map[string]any) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil
+	}
+	requestPath := strings.TrimSuffix(u.Path, "/")
+
+	candidates := []string{requestPath}
+	for _, base := range d.basePaths() {
+		if base != "" && strings.HasPrefix(requestPath, base+"/") {
+			candidates = append(candidates, strings.TrimPrefix(requestPath, base))
+		}
+	}
+
+	method = strings.ToLower(method)
+	paths := mapValue(d.root["paths"])
+	bestScore := -1
+	var bestPath string
+	var bestOp map[string]any
+	for _, path := range sortedKeys(paths) {
+		op := mapValue(d.resolve(paths[path])[method])
+		if op == nil {
+			continue
+		}
+		for _, candidate := range candidates {
+			if score, ok := matchPath(path, candidate); ok && score > bestScore {
+				bestScore, bestPath, bestOp = score, path, op
+			}
+		}
+	}
+	return bestPath, bestOp
+}
+
+// basePaths returns the path part of every server URL
+func (d *Document) basePaths() []string {
+	if d.swagger {
+		return []string{strings.TrimSuffix(stringValue(d.root["basePath"]), "/")}
+	}
+	var paths []string
+	for _, s := range listValue(d.root["servers"]) {
+		server := mapValue(s)
+		raw := stringValue(server["url"])
+		for name, v := range mapValue(server["variables"]) {
+			raw = strings.ReplaceAll(raw, "{"+name+"}", stringValue(mapValue(v)["default"]))
+		}
+		if u, err := url.Parse(raw); err == nil {
+			paths = append(paths, strings.TrimSuffix(u.Path, "/"))
+		}
+	}
+	return paths
+}
+
+// matchPath matches a request path against a template such as /pets/{id},
+// returning the number of literal segments as the match score
+func matchPath(template, path string) (int, bool) {
+	want := strings.Split(strings.Trim(template, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return 0, false
+	}
+
+	score := 0
+	for i, segment := range want {
+		if !strings.Contains(segment, "{") {
+			if segment != got[i] {
+				return 0, false
+			}
+			score++
+			continue
+		}
+		if !segmentPattern(segment).MatchString(got[i]) {
+			return 0, false
+		}
+	}
+	return score, true
+}
+
+// segmentPattern converts a templated path segment such as {name}.json to a regexp
+func segmentPattern(segment string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for segment != "" {
+		start := strings.Index(segment, "{")
+		end := strings.Index(segment, "}")
+		if start < 0 || end < start {
+			b.WriteString(regexp.QuoteMeta(segment))
+			break
+		}
+		b.WriteString(regexp.QuoteMeta(segment[:start]))
+		b.WriteString("[^/]+")
+		segment = segment[end+1:]
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// headerValue looks up a header case-insensitively
+func headerValue(headers map[string]string, key string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// matchMediaType returns the declared type that accepts mediaType, allowing wildcards
+func matchMediaType(mediaType string, declared []string) string {
+	for _, t := range declared {
+		if base, _, err := mime.ParseMediaType(t); err == nil && base == mediaType {
+			return t
+		}
+	}
+	for _, t := range declared {
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+			return t
+		}
+	}
+	for _, t := range declared {
+		if t == "*/*" {
+			return t
+		}
+	}
+	return ""
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// check validates value against schema, recording violations at pointer
+func (d *Document) check(v any, value any, pointer string, result *Validation) {
+	schema := d.resolve(v)
+	if schema == nil {
+		return
+	}
+
+	for _, part := range listValue(schema["allOf"]) {
+		d.check(part, value, pointer, result)
+	}
+	if options := listValue(schema["anyOf"]); len(options) > 0 && d.countMatches(options, value) == 0 {
+		result.add(pointer, "value does not match any of the anyOf schemas")
+	}
+	if options := listValue(schema["oneOf"]); len(options) > 0 {
+		if n := d.countMatches(options, value); n != 1 {
+			result.add(pointer, fmt.Sprintf("value matches %d of the oneOf schemas, expected exactly 1", n))
+		}
+	}
+	if not := schema["not"]; not != nil && d.countMatches([]any{not}, value) == 1 {
+		result.add(pointer, "value must not match the not schema")
+	}
+
+	if enum := listValue(schema["enum"]); len(enum) > 0 {
+		found := false
+		for _, allowed := range enum {
+			if jsonEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result.add(pointer, fmt.Sprintf("value %s is not one of the allowed values", jsonText(value)))
+		}
+	}
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		result.add(pointer, fmt.Sprintf("value must be %s", jsonText(c)))
+	}
+
+	types := schemaTypes(schema)
+	actual := valueType(value)
+	if value == nil {
+		nullable, _ := schema["nullable"].(bool)
+		if len(types) > 0 && !nullable && !containsString(types, "null") {
+			result.add(pointer, fmt.Sprintf("expected %s, got null", strings.Join(types, " or ")))
+		}
+		return
+	}
+	if len(types) > 0 && !typeAllowed(types, actual) {
+		result.add(pointer, fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), actual))
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		d.checkString(schema, value, pointer, result)
+	case json.Number:
+		checkNumber(schema, value, pointer, result)
+	case []any:
+		if n, ok := numberValue(schema["minItems"]); ok && float64(len(value)) < n {
+			result.add(pointer, fmt.Sprintf("expected at least %v items, got %d", n, len(value)))
+		}
+		if n, ok := numberValue(schema["maxItems"]); ok && float64(len(value)) > n {
+			result.add(pointer, fmt.Sprintf("expected at most %v items, got %d", n, len(value)))
+		}
+		if items := schema["items"]; items != nil {
+			for i, item := range value {
+				d.check(items, item, pointer+"/"+strconv.Itoa(i), result)
+			}
+		}
+	case map[string]any:
+		d.checkObject(schema, value, pointer, result)
+	}
+}
+
+// countMatches returns how many schemas value satisfies
+func (d *Document) countMatches(schemas []any, value any) int {
+	n := 0
+	for _, s := range schemas {
+		trial := &Validation{}
+		d.check(s, value, "", trial)
+		if trial.Valid() {
+			n++
+		}
+	}
+	return n
+}
+
+func (d *Document) checkString(schema map[string]any, value, pointer string, result *Validation) {
+	length := float64(utf8.RuneCountInString(value))
+	if n, ok := numberValue(schema["minLength"]); ok && length < n {
+		result.add(pointer, fmt.Sprintf("expected at least %v characters, got %v", n, length))
+	}
+	if n, ok := numberValue(schema["maxLength"]); ok && length > n {
+		result.add(pointer, fmt.Sprintf("expected at most %v characters, got %v", n, length))
+	}
+	if pattern := stringValue(schema["pattern"]); pattern != "" {
+		// Patterns Go cannot compile are skipped rather than reported
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+			result.add(pointer, fmt.Sprintf("value %q does not match pattern %s", value, pattern))
+		}
+	}
+
+	var err error
+	switch stringValue(schema["format"]) {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "uuid":
+		if !uuidPattern.MatchString(value) {
+			err = fmt.Errorf("invalid")
+		}
+	}
+	if err != nil {
+		result.add(pointer, fmt.Sprintf("value %q is not a valid %s", value, stringValue(schema["format"])))
+	}
+}
+
+func checkNumber(schema map[string]any, value json.Number, pointer string, result *Validation) {
+	n, err := value.Float64()
+	if err != nil {
+		return
+	}
+	// OpenAPI 3.0 uses boolean exclusive flags, 3.1 uses numeric bounds
+	if min, ok := numberValue(schema["minimum"]); ok {
+		if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && n <= min {
+			result.add(pointer, fmt.Sprintf("value %s must be greater than %v", value, min))
+		} else if n < min {
+			result.add(pointer, fmt.Sprintf("value %s must be at least %v", value, min))
+		}
+	}
+	if min, ok := numberValue(schema["exclusiveMinimum"]); ok && n <= min {
+		result.add(pointer, fmt.Sprintf("value %s must be greater than %v", value, min))
+	}
+	if max, ok := numberValue(schema["maximum"]); ok {
+		if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && n >= max {
+			result.add(pointer, fmt.Sprintf("value %s must be less than %v", value, max))
+		} else if n > max {
+			result.add(pointer, fmt.Sprintf("value %s must be at most %v", value, max))
+		}
+	}
+	if max, ok := numberValue(schema["exclusiveMaximum"]); ok && n >= max {
+		result.add(pointer, fmt.Sprintf("value %s must be less than %v", value, max))
+	}
+}
+
+func (d *Document) checkObject(schema map[string]any, value map[string]any, pointer string, result *Validation) {
+	for _, r := range listValue(schema["required"]) {
+		name := stringValue(r)
+		if _, ok := value[name]; !ok {
+			result.add(pointer, fmt.Sprintf("missing required property %q", name))
+		}
+	}
+
+	props := mapValue(schema["properties"])
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		child := pointer + "/" + escapePointer(k)
+		if prop, ok := props[k]; ok {
+			d.check(prop, value[k], child, result)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				result.add(child, fmt.Sprintf("property %q is not allowed", k))
+			}
+		case map[string]any:
+			d.check(additional, value[k], child, result)
+		}
+	}
+}
+
+// schemaTypes returns the types a schema allows, from a single type or a 3.1 type list
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			types = append(types, stringValue(item))
+		}
+		return types
+	}
+	return nil
+}
+
+// valueType names the JSON type of a decoded value; whole numbers are integers
+func valueType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if n, err := value.Float64(); err == nil && n == math.Trunc(n) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// typeAllowed reports whether actual satisfies one of types; integers are numbers too
+func typeAllowed(types []string, actual string) bool {
+	return containsString(types, actual) || (actual == "integer" && containsString(types, "number"))
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// numberValue reads a numeric schema keyword
+func numberValue(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// jsonEqual compares a schema value with a decoded body value
+func jsonEqual(schemaValue, value any) bool {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		want, isNumber := numberValue(schemaValue)
+		return err == nil && isNumber && f == want
+	}
+	return reflect.DeepEqual(schemaValue, value)
+}
+
+// jsonText renders a value for messages
+func jsonText(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// escapePointer escapes a JSON pointer reference token
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
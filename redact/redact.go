@@ -0,0 +1,291 @@
+// Package redact masks sensitive values, such as credentials, in requests and
+// responses before they are stored or exported.
+package redact
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"percentman/models"
+)
+
+// Mask replaces redacted values
+const Mask = "[REDACTED]"
+
+// Item masks the values selected by p in the request and response of a
+// history item, and marks the item when anything was masked
+func Item(item *models.HistoryItem, p models.RedactionPolicy) {
+	req := Request(&item.Request, p)
+	resp := Response(&item.Response, p)
+	if req || resp {
+		item.Redacted = true
+	}
+}
+
+// Request masks header values, query parameters and JSON body fields selected
+// by p, and reports whether anything was masked. Values that refer to vault
+// secrets hold nothing secret and are kept, so the request stays reusable.
+func Request(req *models.Request, p models.RedactionPolicy) bool {
+	changed := false
+
+	headers := make([]models.Header, len(req.Headers))
+	copy(headers, req.Headers)
+	for i, h := range headers {
+		if matchName(p.Headers, h.Key) && h.Value != Mask && !models.RefersToSecret(h.Value) {
+			headers[i].Value = Mask
+			changed = true
+		}
+	}
+	req.Headers = headers
+
+	var masked bool
+	req.URL, masked = URL(req.URL, p.Query)
+	changed = changed || masked
+	req.Body, masked = JSON(req.Body, p.Fields)
+	changed = changed || masked
+	if req.GraphQL != nil {
+		graphQL := *req.GraphQL
+		graphQL.Variables, masked = JSON(graphQL.Variables, p.Fields)
+		changed = changed || masked
+		req.GraphQL = &graphQL
+	}
+	return changed
+}
+
+// Response masks header values and JSON body fields selected by p, and
+// reports whether anything was masked
+func Response(resp *models.Response, p models.RedactionPolicy) bool {
+	changed := false
+	if len(resp.Headers) > 0 {
+		headers := make(map[string]string, len(resp.Headers))
+		for key, value := range resp.Headers {
+			if matchName(p.Headers, key) && value != Mask {
+				value = Mask
+				changed = true
+			}
+			headers[key] = value
+		}
+		resp.Headers = headers
+	}
+
+	var masked bool
+	resp.Body, masked = JSON(resp.Body, p.Fields)
+	return changed || masked
+}
+
+// Variables returns a copy of vars with the values of variables named like a
+// header, query parameter or field without dots in p masked
+func Variables(vars map[string]string, p models.RedactionPolicy) map[string]string {
+	var names []string
+	names = append(names, p.Headers...)
+	names = append(names, p.Query...)
+	for _, f := range p.Fields {
+		if !strings.Contains(f, ".") {
+			names = append(names, f)
+		}
+	}
+
+	result := make(map[string]string, len(vars))
+	for name, value := range vars {
+		if matchName(names, name) {
+			value = Mask
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// matchName reports whether name is in names, ignoring case
+func matchName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(strings.TrimSpace(n), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// URL masks the values of the named query parameters, ignoring case. The rest
+// of the URL, including {{variable}} placeholders, is kept as it is.
+func URL(rawURL string, names []string) (string, bool) {
+	start := strings.Index(rawURL, "?")
+	if start < 0 || len(names) == 0 {
+		return rawURL, false
+	}
+	query, fragment := rawURL[start+1:], ""
+	if end := strings.Index(query, "#"); end >= 0 {
+		query, fragment = query[:end], query[end:]
+	}
+
+	changed := false
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || value == Mask || models.RefersToSecret(value) {
+			continue
+		}
+		if decoded, err := url.QueryUnescape(name); err == nil && matchName(names, decoded) {
+			params[i] = name + "=" + Mask
+			changed = true
+		}
+	}
+	if !changed {
+		return rawURL, false
+	}
+	return rawURL[:start+1] + strings.Join(params, "&") + fragment, true
+}
+
+// JSON masks the values of the fields selected by paths in a JSON document,
+// keeping the rest of the text as it is. A path without dots, such as
+// "password", selects the field at any depth; a dotted path such as
+// "user.credentials.*.secret" selects from the root, where "*" matches any
+// key or array index. Text that is not JSON is returned unchanged; in a
+// truncated document, the fields before the end are masked.
+func JSON(text string, paths []string) (string, bool) {
+	trimmed := strings.TrimSpace(text)
+	if len(paths) == 0 || (!strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[")) {
+		return text, false
+	}
+	patterns := make([][]string, 0, len(paths))
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, strings.Split(p, "."))
+		}
+	}
+
+	// level is an open object or array. Objects alternate keys and values;
+	// segment is the key or index of the value being read.
+	type level struct {
+		object  bool
+		keyNext bool
+		index   int
+		segment string
+	}
+	var stack []level
+	path := func() []string {
+		segments := make([]string, len(stack))
+		for i, l := range stack {
+			segments[i] = l.segment
+		}
+		return segments
+	}
+	valueDone := func() {
+		if n := len(stack); n > 0 {
+			if stack[n-1].object {
+				stack[n-1].keyNext = true
+			} else {
+				stack[n-1].index++
+				stack[n-1].segment = strconv.Itoa(stack[n-1].index)
+			}
+		}
+	}
+
+	type span struct{ start, end int }
+	var spans []span
+	dec := json.NewDecoder(strings.NewReader(text))
+	// skipValue masks the next value, which may be an object or array
+	skipValue := func() bool {
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return false
+		}
+		if string(value) == strconv.Quote(Mask) {
+			return true
+		}
+		end := int(dec.InputOffset())
+		spans = append(spans, span{end - len(value), end})
+		return true
+	}
+
+scan:
+	for {
+		// Array elements are selected before reading them
+		if n := len(stack); n > 0 && !stack[n-1].object && dec.More() && matchPath(patterns, path(), false) {
+			if !skipValue() {
+				break
+			}
+			valueDone()
+			continue
+		}
+
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+
+		if n := len(stack); n > 0 && stack[n-1].keyNext {
+			if tok == json.Delim('}') {
+				stack = stack[:n-1]
+				valueDone()
+				continue
+			}
+			key, _ := tok.(string)
+			stack[n-1].keyNext = false
+			stack[n-1].segment = key
+			if matchPath(patterns, path(), true) {
+				if !skipValue() {
+					break scan
+				}
+				stack[n-1].keyNext = true
+			}
+			continue
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, level{object: true, keyNext: true})
+		case json.Delim('['):
+			stack = append(stack, level{segment: "0"})
+		case json.Delim(']'), json.Delim('}'):
+			stack = stack[:len(stack)-1]
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+
+	if len(spans) == 0 {
+		return text, false
+	}
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(text[last:sp.start])
+		b.WriteString(strconv.Quote(Mask))
+		last = sp.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), true
+}
+
+// matchPath reports whether any pattern selects the value at path. Patterns
+// without dots only select object fields, named by the last segment of path.
+func matchPath(patterns [][]string, path []string, field bool) bool {
+	for _, pattern := range patterns {
+		if len(pattern) == 1 {
+			if !field {
+				continue
+			}
+			if len(path) > 0 && strings.EqualFold(pattern[0], path[len(path)-1]) {
+				return true
+			}
+			continue
+		}
+		if len(pattern) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range pattern {
+			if segment != "*" && !strings.EqualFold(segment, path[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1012 @@
+//go:build !js
+
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"percentman/models"
+	"percentman/redact"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// templatesBucket maps template IDs to templates
+	templatesBucket = []byte("templates")
+	// historyBucket maps time-ordered keys to history items; see historyKey
+	historyBucket = []byte("history")
+	// historyIDBucket indexes historyBucket by item ID
+	historyIDBucket = []byte("history_ids")
+	// historySummaryBucket maps the keys of historyBucket to the fields that
+	// searches filter on, so most items need not be decoded in full
+	historySummaryBucket = []byte("history_summaries")
+	// revisionsBucket maps template IDs to their earlier versions, newest first
+	revisionsBucket = []byte("revisions")
+	// metaBucket holds the settings and the schema version
+	metaBucket = []byte("meta")
+
+	settingsKey = []byte("settings")
+	versionKey  = []byte("version")
+)
+
+// BoltStore keeps a workspace in an embedded bbolt database. History items
+// are written one at a time and looked up through an index, so large
+// histories stay fast. Templates are also kept in memory, as they are few.
+type BoltStore struct {
+	mu        sync.RWMutex
+	dataDir   string
+	db        *bolt.DB
+	templates []models.Template
+	settings  models.Settings
+	// historyCount tracks the size of historyBucket to apply the retention settings
+	historyCount int
+	// watcher reports changes to the collection directory while watching
+	watcher *fsnotify.Watcher
+	// loadErrors are the JSON files that could not be read when importing them
+	loadErrors []LoadError
+}
+
+// NewBoltStore opens the database of the workspace in dataDir, or of the
+// default workspace if dataDir is empty. A new database starts with the
+// workspace's JSON files, if it has any.
+func NewBoltStore(dataDir string) (*BoltStore, error) {
+	dataDir, err := workspaceDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := rememberWorkspace(dataDir); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dataDir, boltFile)
+	_, statErr := os.Stat(path)
+	fresh := os.IsNotExist(statErr)
+
+	// bbolt locks the file, so only one process can have it open
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: lockTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, &LockedError{Dir: dataDir}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BoltStore{
+		dataDir:   dataDir,
+		db:        db,
+		templates: []models.Template{},
+		settings:  models.DefaultSettings(),
+	}
+	if err := s.init(fresh); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// openBolt opens a BoltStore as a Store
+func openBolt(dataDir string) (Store, error) {
+	s, err := NewBoltStore(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// init creates the buckets, checks the schema version, imports the JSON files
+// of a new database and loads templates and settings
+func (s *BoltStore) init(fresh bool) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{templatesBucket, historyBucket, historyIDBucket, historySummaryBucket, revisionsBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		// Databases written before the summaries existed, or migrated since,
+		// get them from the history items
+		stale := tx.Bucket(historySummaryBucket).Stats().KeyN != tx.Bucket(historyBucket).Stats().KeyN
+		meta := tx.Bucket(metaBucket)
+		if v := meta.Get(versionKey); v != nil {
+			version, err := strconv.Atoi(string(v))
+			if err != nil {
+				return err
+			}
+			if version > schemaVersion {
+				return &VersionError{File: boltFile, Version: version}
+			}
+			if err := upgradeBolt(tx, version); err != nil {
+				return err
+			}
+			stale = stale || version < schemaVersion
+		}
+		if stale {
+			if err := summarizeHistory(tx); err != nil {
+				return err
+			}
+		}
+		return meta.Put(versionKey, []byte(strconv.Itoa(schemaVersion)))
+	})
+	if err != nil {
+		return err
+	}
+
+	if fresh {
+		if err := s.importJSON(); err != nil {
+			return err
+		}
+	}
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		if data := tx.Bucket(metaBucket).Get(settingsKey); data != nil {
+			if err := json.Unmarshal(data, &s.settings); err != nil {
+				return err
+			}
+		}
+		templates, err := s.readTemplates(tx)
+		if err != nil {
+			return err
+		}
+		s.templates = templates
+		s.historyCount = tx.Bucket(historyBucket).Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Items may have expired since the database was last open
+	return s.db.Update(s.trimHistory)
+}
+
+// upgradeBolt migrates the data of a database written at an older schema
+// version, using the migrations of the JSON file holding the same data
+func upgradeBolt(tx *bolt.Tx, version int) error {
+	meta := tx.Bucket(metaBucket)
+	if data := meta.Get(settingsKey); data != nil {
+		upgraded, err := upgrade(settingsFile, version, data)
+		if err != nil {
+			return err
+		}
+		if err := meta.Put(settingsKey, upgraded); err != nil {
+			return err
+		}
+	}
+
+	// The files hold lists of the values the buckets hold one by one; the
+	// migrations keep the order and number of items
+	for name, bucket := range map[string][]byte{templatesFile: templatesBucket, historyFile: historyBucket} {
+		if !needsMigration(name, version) {
+			continue
+		}
+		b := tx.Bucket(bucket)
+		var keys [][]byte
+		var values []json.RawMessage
+		b.ForEach(func(k, v []byte) error {
+			keys = append(keys, append([]byte(nil), k...))
+			values = append(values, append(json.RawMessage(nil), v...))
+			return nil
+		})
+		list, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		if list, err = upgrade(name, version, list); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(list, &values); err != nil {
+			return err
+		}
+		if len(values) != len(keys) {
+			return fmt.Errorf("upgrading %s changed the number of items", name)
+		}
+		for i, k := range keys {
+			if err := b.Put(k, values[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// needsMigration reports whether the named file has migrations from version on
+func needsMigration(name string, version int) bool {
+	for v := version; v < schemaVersion; v++ {
+		if _, ok := migrations[name][v]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// importJSON copies the workspace's JSON files into the database
+func (s *BoltStore) importJSON() error {
+	files := []string{templatesFile, historyFile, settingsFile}
+	found := false
+	for _, name := range files {
+		if _, err := os.Stat(filepath.Join(s.dataDir, name)); err == nil {
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	old, err := NewStorage(s.dataDir)
+	if err != nil {
+		return err
+	}
+	// Unreadable files were moved aside; their backups can still be restored
+	s.loadErrors = old.LoadErrors()
+	settings := old.GetSettings()
+	revisions, err := old.readRevisions()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := putJSON(tx.Bucket(metaBucket), settingsKey, settings); err != nil {
+			return err
+		}
+		for _, h := range old.GetHistory() {
+			if err := putHistory(tx, h); err != nil {
+				return err
+			}
+		}
+		for id, r := range revisions {
+			if err := putJSON(tx.Bucket(revisionsBucket), []byte(id), r); err != nil {
+				return err
+			}
+		}
+		// Templates kept in a collection directory stay there
+		if settings.Collection != "" {
+			return nil
+		}
+		for _, t := range old.GetTemplates() {
+			if err := putJSON(tx.Bucket(templatesBucket), []byte(t.ID), t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Dir returns the data directory of the workspace
+func (s *BoltStore) Dir() string {
+	return s.dataDir
+}
+
+// Close closes the database
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Templates
+
+// readTemplates reads the templates from the collection directory or the database
+func (s *BoltStore) readTemplates(tx *bolt.Tx) ([]models.Template, error) {
+	if s.settings.Collection != "" {
+		return loadCollection(s.settings.Collection)
+	}
+
+	templates := []models.Template{}
+	err := tx.Bucket(templatesBucket).ForEach(func(_, data []byte) error {
+		var t models.Template
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		templates = append(templates, t)
+		return nil
+	})
+	sortTemplates(templates)
+	return templates, err
+}
+
+// writeTemplates stores the changed templates and removes deleted ones. A
+// collection directory is rewritten instead, leaving unchanged files alone.
+func (s *BoltStore) writeTemplates(changed []models.Template, deleted []string) error {
+	if s.settings.Collection != "" {
+		return saveCollection(s.settings.Collection, s.templates, deleted)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(templatesBucket)
+		for _, t := range changed {
+			if err := putJSON(b, []byte(t.ID), t); err != nil {
+				return err
+			}
+		}
+		for _, id := range deleted {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetTemplates returns all templates
+func (s *BoltStore) GetTemplates() []models.Template {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]models.Template, len(s.templates))
+	copy(result, s.templates)
+	return result
+}
+
+// SaveTemplate saves req as a new template called name, replacing the
+// template already called that, if any
+func (s *BoltStore) SaveTemplate(name string, req *models.Request) (*models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := cloneTemplates(s.templates)
+	var saved models.Template
+	var replaced string
+	s.templates, saved, replaced = addTemplate(s.templates, name, req, time.Now())
+	if err := s.keepRevisions(before); err != nil {
+		return nil, err
+	}
+	var deleted []string
+	if replaced != "" {
+		deleted = []string{replaced}
+	}
+	if err := s.writeTemplates([]models.Template{saved}, deleted); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// UpdateTemplate replaces the request of the template with the given ID. A
+// revision other than 0 must be the current one, or ErrTemplateChanged is returned.
+func (s *BoltStore) UpdateTemplate(id string, req *models.Request, revision int) (*models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := cloneTemplates(s.templates)
+	saved, err := updateTemplate(s.templates, id, req, revision, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.keepRevisions(before); err != nil {
+		return nil, err
+	}
+	if err := s.writeTemplates([]models.Template{saved}, nil); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// RenameTemplate renames the template with the given ID
+func (s *BoltStore) RenameTemplate(id, name string) (*models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var renamed models.Template
+	var err error
+	if s.templates, renamed, err = renameTemplate(s.templates, id, name, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.writeTemplates([]models.Template{renamed}, nil); err != nil {
+		return nil, err
+	}
+	return &renamed, nil
+}
+
+// DuplicateTemplate saves a copy of the template with the given ID
+func (s *BoltStore) DuplicateTemplate(id string) (*models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var copied models.Template
+	var err error
+	if s.templates, copied, err = duplicateTemplate(s.templates, id, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.writeTemplates([]models.Template{copied}, nil); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// ImportTemplates adds imported templates. A template whose Source matches an
+// existing template replaces that template's request instead of being added again.
+func (s *BoltStore) ImportTemplates(templates []models.Template) (created, updated int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := cloneTemplates(s.templates)
+	var changed []models.Template
+	s.templates, changed, created, updated = importTemplates(s.templates, templates, time.Now())
+	if err := s.keepRevisions(before); err != nil {
+		return 0, 0, err
+	}
+	return created, updated, s.writeTemplates(changed, nil)
+}
+
+// DeleteTemplate deletes a template by ID
+func (s *BoltStore) DeleteTemplate(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := cloneTemplates(s.templates)
+	var removed bool
+	if s.templates, removed = removeTemplate(s.templates, id); removed {
+		if err := s.keepRevisions(before); err != nil {
+			return err
+		}
+		return s.writeTemplates(nil, []string{id})
+	}
+	return nil
+}
+
+// GetTemplateByID returns a template by ID
+func (s *BoltStore) GetTemplateByID(id string) *models.Template {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return findTemplate(s.templates, id)
+}
+
+// TemplateNameExists checks if a template name already exists
+func (s *BoltStore) TemplateNameExists(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return hasTemplateName(s.templates, name)
+}
+
+// keepRevisions records the versions of templates that a change to
+// s.templates replaces, and forgets those of removed templates. Callers hold s.mu.
+func (s *BoltStore) keepRevisions(before []models.Template) error {
+	replaced, removed := replacedTemplates(before, s.templates)
+	if len(replaced) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(revisionsBucket)
+		for _, t := range replaced {
+			revisions, err := getRevisions(b, t.ID)
+			if err != nil {
+				return err
+			}
+			if err := putJSON(b, []byte(t.ID), addRevision(revisions, t)); err != nil {
+				return err
+			}
+		}
+		for _, id := range removed {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// getRevisions reads the earlier versions of a template from b
+func getRevisions(b *bolt.Bucket, id string) ([]models.TemplateRevision, error) {
+	var revisions []models.TemplateRevision
+	if data := b.Get([]byte(id)); data != nil {
+		if err := json.Unmarshal(data, &revisions); err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+// TemplateRevisions returns the earlier versions of a template, newest first
+func (s *BoltStore) TemplateRevisions(id string) ([]models.TemplateRevision, error) {
+	var revisions []models.TemplateRevision
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		revisions, err = getRevisions(tx.Bucket(revisionsBucket), id)
+		return err
+	})
+	return revisions, err
+}
+
+// RestoreTemplate makes an earlier version of a template its current one.
+// The version it replaces is kept as a revision too.
+func (s *BoltStore) RestoreTemplate(id string, revision int) (*models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions, err := s.TemplateRevisions(id)
+	if err != nil {
+		return nil, err
+	}
+	r, err := findRevision(revisions, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	before := cloneTemplates(s.templates)
+	restored, err := updateTemplate(s.templates, id, &r.Request, 0, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.keepRevisions(before); err != nil {
+		return nil, err
+	}
+	if err := s.writeTemplates([]models.Template{restored}, nil); err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
+
+// History
+
+// historyKey orders history items by time, oldest first. The ID follows the
+// time so that items sent at the same time, or without one, keep their own keys.
+func historyKey(h models.HistoryItem) []byte {
+	return append(timeKey(h.Timestamp), h.ID...)
+}
+
+// putHistory stores one history item, indexes it by ID and stores its summary
+func putHistory(tx *bolt.Tx, h models.HistoryItem) error {
+	key := historyKey(h)
+	if err := putJSON(tx.Bucket(historyBucket), key, h); err != nil {
+		return err
+	}
+	if err := putJSON(tx.Bucket(historySummaryBucket), key, summarize(&h)); err != nil {
+		return err
+	}
+	return tx.Bucket(historyIDBucket).Put([]byte(h.ID), key)
+}
+
+// summarizeHistory rebuilds the summaries of all history items
+func summarizeHistory(tx *bolt.Tx) error {
+	if err := tx.DeleteBucket(historySummaryBucket); err != nil {
+		return err
+	}
+	summaries, err := tx.CreateBucket(historySummaryBucket)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(historyBucket).ForEach(func(k, v []byte) error {
+		var h models.HistoryItem
+		if err := json.Unmarshal(v, &h); err != nil {
+			return fmt.Errorf("history item %x: %w", k, err)
+		}
+		return putJSON(summaries, k, summarize(&h))
+	})
+}
+
+// timeKey is the prefix of the history keys of items sent at t. UnixNano is
+// undefined outside the years 1678 to 2262, so earlier times, the zero time
+// among them, share the first key and later ones the last.
+func timeKey(t time.Time) []byte {
+	var nanos uint64
+	switch {
+	case t.Before(time.Unix(0, 0)):
+	case t.After(time.Unix(0, math.MaxInt64)):
+		nanos = math.MaxInt64
+	default:
+		nanos = uint64(t.UnixNano())
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, nanos)
+	return key
+}
+
+// trimHistory drops the oldest items that the retention settings no longer keep
+func (s *BoltStore) trimHistory(tx *bolt.Tx) error {
+	r := s.settings.History
+	var cutoff []byte
+	if r.MaxAgeDays > 0 {
+		cutoff = timeKey(historyCutoff(r, time.Now()))
+	}
+
+	c := tx.Bucket(historyBucket).Cursor()
+	ids := tx.Bucket(historyIDBucket)
+	summaries := tx.Bucket(historySummaryBucket)
+	for k, _ := c.First(); k != nil; k, _ = c.First() {
+		tooMany := r.MaxItems > 0 && s.historyCount > r.MaxItems
+		tooOld := cutoff != nil && bytes.Compare(k[:8], cutoff) < 0
+		if !tooMany && !tooOld {
+			break
+		}
+		if err := ids.Delete(k[8:]); err != nil {
+			return err
+		}
+		if err := summaries.Delete(k); err != nil {
+			return err
+		}
+		if err := c.Delete(); err != nil {
+			return err
+		}
+		s.historyCount--
+	}
+	return nil
+}
+
+// GetHistory returns all history items, newest first
+func (s *BoltStore) GetHistory() []models.HistoryItem {
+	items, _ := s.SearchHistory(HistoryQuery{})
+	return items
+}
+
+// SearchHistory returns the page of history items selected by q, newest
+// first, and how many items it selects in total. The date range is looked up
+// through the time-ordered keys and the other filters, but for the text,
+// through the summaries; only a text search decodes every item in the range.
+func (s *BoltStore) SearchHistory(q HistoryQuery) ([]models.HistoryItem, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filtered := q.Method != "" || q.StatusClass != 0 || q.Failed || q.ErrorKind != "" || q.Host != ""
+	items := []models.HistoryItem{}
+	total := 0
+	s.db.View(func(tx *bolt.Tx) error {
+		history := tx.Bucket(historyBucket)
+		c := tx.Bucket(historySummaryBucket).Cursor()
+		k, v := c.Last()
+		if !q.To.IsZero() {
+			if k, v = c.Seek(timeKey(q.To)); k != nil {
+				k, v = c.Prev()
+			} else {
+				k, v = c.Last()
+			}
+		}
+		var from []byte
+		if !q.From.IsZero() {
+			from = timeKey(q.From)
+		}
+
+		for ; k != nil; k, v = c.Prev() {
+			if from != nil && bytes.Compare(k[:8], from) < 0 {
+				break
+			}
+			if filtered {
+				var sum historySummary
+				if err := json.Unmarshal(v, &sum); err != nil || !q.matchesSummary(sum) {
+					continue
+				}
+			}
+			inPage := total >= q.Offset && (q.Limit == 0 || len(items) < q.Limit)
+			if q.Text == "" && !inPage {
+				total++
+				continue
+			}
+			var h models.HistoryItem
+			if err := json.Unmarshal(history.Get(k), &h); err != nil || !q.matchesText(&h) {
+				continue
+			}
+			if inPage {
+				items = append(items, h)
+			}
+			total++
+		}
+		return nil
+	})
+	return items, total
+}
+
+// AddHistory adds a new history item
+func (s *BoltStore) AddHistory(req *models.Request, resp *models.Response) (*models.HistoryItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := models.HistoryItem{
+		ID:        uuid.New().String(),
+		Request:   *req.Clone(),
+		Response:  *resp,
+		Timestamp: time.Now(),
+	}
+	redact.Item(&item, s.settings.Redaction)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := putHistory(tx, item); err != nil {
+			return err
+		}
+		s.historyCount++
+		return s.trimHistory(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ImportHistory adds history items recorded elsewhere
+func (s *BoltStore) ImportHistory(items []models.HistoryItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, item := range items {
+			item.ID = uuid.New().String()
+			item.Request = *item.Request.Clone()
+			redact.Item(&item, s.settings.Redaction)
+			if err := putHistory(tx, item); err != nil {
+				return err
+			}
+			s.historyCount++
+		}
+		return s.trimHistory(tx)
+	})
+}
+
+// ClearHistory clears all history
+func (s *BoltStore) ClearHistory() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{historyBucket, historyIDBucket, historySummaryBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		s.historyCount = 0
+	}
+	return err
+}
+
+// GetHistoryByID returns a history item by ID
+func (s *BoltStore) GetHistoryByID(id string) *models.HistoryItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var item *models.HistoryItem
+	s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(historyIDBucket).Get([]byte(id))
+		if key == nil {
+			return nil
+		}
+		var h models.HistoryItem
+		if err := json.Unmarshal(tx.Bucket(historyBucket).Get(key), &h); err == nil {
+			item = &h
+		}
+		return nil
+	})
+	return item
+}
+
+// Settings
+
+// GetSettings returns the app-wide settings
+func (s *BoltStore) GetSettings() models.Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.settings
+}
+
+// SaveSettings replaces the app-wide settings. Changing the collection
+// directory switches where templates are kept: an existing collection is
+// loaded, otherwise the current templates are written to the new location.
+func (s *BoltStore) SaveSettings(settings models.Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if settings.Collection != s.settings.Collection {
+		previous := s.settings.Collection
+		s.settings.Collection = settings.Collection
+		var templates []models.Template
+		err := s.db.View(func(tx *bolt.Tx) error {
+			var err error
+			templates, err = s.readTemplates(tx)
+			return err
+		})
+		if err == nil {
+			if len(templates) == 0 {
+				err = s.writeTemplates(s.templates, nil)
+			} else {
+				s.templates = templates
+			}
+		}
+		if err != nil {
+			s.settings.Collection = previous
+			return err
+		}
+		s.watchDirs()
+	}
+
+	retention := s.settings.History
+	s.settings.History = settings.History
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := putJSON(tx.Bucket(metaBucket), settingsKey, settings); err != nil {
+			return err
+		}
+		if settings.History != retention {
+			return s.trimHistory(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		s.settings.History = retention
+		return err
+	}
+	s.settings = settings
+	return nil
+}
+
+// Maintenance
+
+// Watch reloads templates whenever files in the collection directory change,
+// and calls onChange from a background goroutine when they changed. The
+// database itself needs no watching: while it is open no other process can
+// open it. Call the returned function to stop watching.
+func (s *BoltStore) Watch(onChange func(Changes)) (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.watcher = watcher
+	s.watchDirs()
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go runWatcher(watcher, s.dataDir, s.Reload, onChange, done)
+
+	return func() {
+		close(done)
+		watcher.Close()
+		s.mu.Lock()
+		s.watcher = nil
+		s.mu.Unlock()
+	}, nil
+}
+
+// watchDirs adds the collection directory with its folders to the watcher,
+// if one is running. Callers hold s.mu.
+func (s *BoltStore) watchDirs() {
+	if s.watcher != nil {
+		addDirs(s.watcher, collectionDirs(s.settings.Collection))
+	}
+}
+
+// Reload re-reads the templates of a collection directory and reports whether
+// they differ from what is held in memory
+func (s *BoltStore) Reload() (Changes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changes Changes
+	if s.settings.Collection == "" {
+		return changes, nil
+	}
+	templates, err := loadCollection(s.settings.Collection)
+	if err != nil {
+		return changes, err
+	}
+	if !sameJSON(withoutTimes(templates), withoutTimes(s.templates)) {
+		s.templates = templates
+		changes.Templates = true
+	}
+	return changes, nil
+}
+
+// LoadErrors returns the JSON files that could not be read when the database
+// was created from them. Unreadable databases fail to open instead.
+func (s *BoltStore) LoadErrors() []LoadError {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]LoadError, len(s.loadErrors))
+	copy(result, s.loadErrors)
+	return result
+}
+
+// DismissLoadError forgets a load error without restoring a backup
+func (s *BoltStore) DismissLoadError(file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, le := range s.loadErrors {
+		if le.File == file {
+			s.loadErrors = append(s.loadErrors[:i], s.loadErrors[i+1:]...)
+			return
+		}
+	}
+}
+
+// RestoreBackup adds the data of the newest readable backup of a JSON file
+// that could not be imported. The embedded database keeps no backups itself.
+func (s *BoltStore) RestoreBackup(file string) error {
+	s.mu.RLock()
+	var backup string
+	for _, le := range s.loadErrors {
+		if le.File == file {
+			backup = le.Backup
+		}
+	}
+	s.mu.RUnlock()
+	if backup == "" {
+		return fmt.Errorf("no backup of %s to restore", filepath.Base(file))
+	}
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return err
+	}
+
+	switch file {
+	case filepath.Join(s.dataDir, templatesFile):
+		var templates []models.Template
+		if err := decodeFile(templatesFile, data, &templates); err != nil {
+			return err
+		}
+		err = s.restoreTemplates(templates)
+	case filepath.Join(s.dataDir, historyFile):
+		var history []models.HistoryItem
+		if err := decodeFile(historyFile, data, &history); err != nil {
+			return err
+		}
+		err = s.restoreHistory(history)
+	case filepath.Join(s.dataDir, settingsFile):
+		settings := models.DefaultSettings()
+		if err := decodeFile(settingsFile, data, &settings); err != nil {
+			return err
+		}
+		err = s.SaveSettings(settings)
+	default:
+		return fmt.Errorf("no backup of %s to restore", filepath.Base(file))
+	}
+	if err != nil {
+		return err
+	}
+	s.DismissLoadError(file)
+	return nil
+}
+
+// restoreTemplates replaces the templates with ones read from a backup
+func (s *BoltStore) restoreTemplates(templates []models.Template) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := removedIDs(s.templates, templates)
+	sortTemplates(templates)
+	s.templates = templates
+	return s.writeTemplates(templates, deleted)
+}
+
+// restoreHistory adds history items read from a backup, keeping their IDs.
+// Items already in the history are left as they are.
+func (s *BoltStore) restoreHistory(history []models.HistoryItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		ids := tx.Bucket(historyIDBucket)
+		for _, h := range history {
+			if ids.Get([]byte(h.ID)) != nil {
+				continue
+			}
+			if err := putHistory(tx, h); err != nil {
+				return err
+			}
+			s.historyCount++
+		}
+		return s.trimHistory(tx)
+	})
+}
+
+// putJSON stores v under key
+func putJSON(b *bolt.Bucket, key []byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
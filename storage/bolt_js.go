@@ -0,0 +1,10 @@
+//go:build js
+
+package storage
+
+import "errors"
+
+// openBolt fails, as the embedded database needs a file system
+func openBolt(dataDir string) (Store, error) {
+	return nil, errors.New("the embedded database is not supported on this platform")
+}
@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"percentman/models"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// folderFile holds the metadata of a folder in a collection directory
+const folderFile = "folder.yaml"
+
+// requestFile is the on-disk form of one template in a collection directory.
+// Timestamps are left out so saving an unchanged template leaves its file unchanged.
+type requestFile struct {
+	ID       string         `yaml:"id"`
+	Name     string         `yaml:"name"`
+	Source   string         `yaml:"source,omitempty"`
+	Revision int            `yaml:"revision,omitempty"`
+	Request  models.Request `yaml:"request"`
+}
+
+// folderMeta is the content of a folder.yaml file
+type folderMeta struct {
+	Name string `yaml:"name"`
+}
+
+// loadCollection reads the templates of a collection directory: top-level
+// request files, plus one subdirectory per folder
+func loadCollection(dir string) ([]models.Template, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []models.Template{}, nil
+		}
+		return nil, err
+	}
+
+	templates := []models.Template{}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() {
+			folder, err := loadFolder(path)
+			if err != nil {
+				return nil, err
+			}
+			templates = append(templates, folder...)
+			continue
+		}
+		if !isRequestFile(e.Name()) {
+			continue
+		}
+		t, err := loadRequestFile(path)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// loadFolder reads the request files of one folder directory
+func loadFolder(dir string) ([]models.Template, error) {
+	meta := folderMeta{Name: filepath.Base(dir)}
+	if data, err := os.ReadFile(filepath.Join(dir, folderFile)); err == nil {
+		if err := yaml.Unmarshal(data, &meta); err != nil {
+			return nil, &parseError{filepath.Join(dir, folderFile), err}
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var templates []models.Template
+	for _, e := range entries {
+		if e.IsDir() || !isRequestFile(e.Name()) {
+			continue
+		}
+		t, err := loadRequestFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		t.Folder = meta.Name
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// loadRequestFile reads one template
+func loadRequestFile(path string) (models.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return models.Template{}, err
+	}
+	var f requestFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return models.Template{}, &parseError{path, err}
+	}
+
+	// Hand-written files may leave these out; the ID, derived from the path so
+	// that every read agrees on it, is written back on the next save
+	if f.ID == "" {
+		f.ID = uuid.NewSHA1(uuid.NameSpaceURL, []byte("file://"+filepath.ToSlash(path))).String()
+	}
+	if f.Name == "" {
+		f.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if f.Request.Headers == nil {
+		f.Request.Headers = []models.Header{}
+	}
+
+	t := models.Template{
+		ID:       f.ID,
+		Name:     f.Name,
+		Source:   f.Source,
+		Revision: f.Revision,
+		Request:  f.Request,
+	}
+	if info, err := os.Stat(path); err == nil {
+		t.CreatedAt = info.ModTime()
+		t.UpdatedAt = info.ModTime()
+	}
+	return t, nil
+}
+
+// collectionFiles lists the request files in dir by the ID of the template
+// they hold, and the folder directories by folder name
+func collectionFiles(dir string) (files, folders map[string]string, err error) {
+	files = make(map[string]string)
+	folders = make(map[string]string)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	addFile := func(rel string) {
+		if t, err := loadRequestFile(filepath.Join(dir, rel)); err == nil {
+			files[t.ID] = filepath.ToSlash(rel)
+		}
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if !e.IsDir() {
+			if isRequestFile(e.Name()) {
+				addFile(e.Name())
+			}
+			continue
+		}
+		meta := folderMeta{Name: e.Name()}
+		if data, err := os.ReadFile(filepath.Join(dir, e.Name(), folderFile)); err == nil {
+			yaml.Unmarshal(data, &meta)
+		}
+		folders[meta.Name] = e.Name()
+		sub, err := os.ReadDir(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range sub {
+			if !f.IsDir() && isRequestFile(f.Name()) {
+				addFile(filepath.Join(e.Name(), f.Name()))
+			}
+		}
+	}
+	return files, folders, nil
+}
+
+// saveCollection writes each template to its own file, leaving unchanged files
+// untouched, and removes the files of deleted templates. Templates keep their
+// file while they stay in the same folder, and other files are left alone, so
+// that files added by hand or by a git pull survive.
+func saveCollection(dir string, templates []models.Template, deleted []string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	onDisk, diskFolders, err := collectionFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	// New files and folders get names nothing in the directory has yet
+	used := make(map[string]bool)
+	for _, rel := range onDisk {
+		used[strings.TrimSuffix(rel, path.Ext(rel))] = true
+	}
+	for _, folderDir := range diskFolders {
+		used[folderDir] = true
+	}
+
+	folders := make(map[string]string)
+	for _, t := range templates {
+		if t.Folder == "" || folders[t.Folder] != "" {
+			continue
+		}
+		folderDir := diskFolders[t.Folder]
+		if folderDir == "" {
+			folderDir = uniqueName(fileSlug(t.Folder), "", used)
+		}
+		folders[t.Folder] = folderDir
+		meta, err := marshalYAML(folderMeta{Name: t.Folder})
+		if err != nil {
+			return err
+		}
+		if err := writeIfChanged(filepath.Join(dir, folderDir, folderFile), meta); err != nil {
+			return err
+		}
+	}
+
+	var stale []string
+	for _, t := range templates {
+		folderDir := folders[t.Folder]
+		rel := onDisk[t.ID]
+		if rel == "" || path.Dir(rel) != path.Clean("./"+folderDir) {
+			// A moved template would load twice from its earlier file
+			if rel != "" {
+				stale = append(stale, rel)
+			}
+			rel = path.Join(folderDir, uniqueName(fileSlug(t.Name), folderDir, used)+".yaml")
+		}
+
+		data, err := marshalYAML(requestFile{
+			ID:       t.ID,
+			Name:     t.Name,
+			Source:   t.Source,
+			Revision: t.Revision,
+			Request:  t.Request,
+		})
+		if err != nil {
+			return err
+		}
+		if err := writeIfChanged(filepath.Join(dir, filepath.FromSlash(rel)), data); err != nil {
+			return err
+		}
+	}
+	for _, id := range deleted {
+		if rel := onDisk[id]; rel != "" {
+			stale = append(stale, rel)
+		}
+	}
+
+	return removeStale(dir, stale)
+}
+
+// removeStale deletes the given request files, then the folder directories
+// they leave without request files
+func removeStale(dir string, stale []string) error {
+	emptied := make(map[string]bool)
+	for _, rel := range stale {
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(rel))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if folderDir := path.Dir(rel); folderDir != "." {
+			emptied[folderDir] = true
+		}
+	}
+
+	for folderDir := range emptied {
+		folderPath := filepath.Join(dir, filepath.FromSlash(folderDir))
+		entries, err := os.ReadDir(folderPath)
+		if err != nil {
+			continue
+		}
+		if slices.ContainsFunc(entries, func(e os.DirEntry) bool { return isRequestFile(e.Name()) }) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(folderPath, folderFile)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// Only succeeds when nothing else is left in the folder
+		os.Remove(folderPath)
+	}
+	return nil
+}
+
+// isRequestFile reports whether a file name is a request file
+func isRequestFile(name string) bool {
+	return name != folderFile && (strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml"))
+}
+
+// marshalYAML encodes v with two-space indentation. Multi-line strings such as
+// bodies are written as literal blocks.
+func marshalYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeIfChanged writes data to path unless the file already holds it
+func writeIfChanged(path string, data []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	return writeFile(path, data, 0)
+}
+
+// fileSlug turns a name into a lower-case file name such as "list-users"
+func fileSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if b.Len() == 0 {
+		return "request"
+	}
+	return b.String()
+}
+
+// uniqueName returns slug, or slug with a numeric suffix if another entry in
+// the same directory already uses it
+func uniqueName(slug, dir string, used map[string]bool) string {
+	name := slug
+	for i := 2; used[path.Join(dir, name)]; i++ {
+		name = fmt.Sprintf("%s-%d", slug, i)
+	}
+	used[path.Join(dir, name)] = true
+	return name
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"percentman/models"
+)
+
+// maxBackups is how many previous versions of each data file are kept
+const maxBackups = 3
+
+// parseError reports a data file whose content could not be decoded
+type parseError struct {
+	path string
+	err  error
+}
+
+func (e *parseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.path, e.err)
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+// LoadError describes a data file that could not be read when the workspace
+// was opened. The file is moved aside so that later saves cannot overwrite it.
+type LoadError struct {
+	// File is the path the unreadable file was loaded from
+	File string
+	Err  error
+	// Kept is where the unreadable file was moved
+	Kept string
+	// Backup is the newest backup that reads correctly, or "" if there is none
+	Backup string
+}
+
+// LoadErrors returns the data files that could not be read and were not restored yet
+func (s *Storage) LoadErrors() []LoadError {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]LoadError, len(s.loadErrors))
+	copy(result, s.loadErrors)
+	return result
+}
+
+// recoverFile moves an unreadable file aside, finds its newest readable
+// backup and records both as a LoadError
+func (s *Storage) recoverFile(perr *parseError, valid func([]byte) error) {
+	le := LoadError{
+		File: perr.path,
+		Err:  perr.err,
+		Kept: perr.path + ".corrupt-" + time.Now().Format("20060102-150405"),
+	}
+	if err := os.Rename(perr.path, le.Kept); err != nil {
+		log.Printf("Failed to move aside %s: %v", perr.path, err)
+		le.Kept = ""
+	}
+	for i := 1; i <= maxBackups; i++ {
+		data, err := os.ReadFile(backupName(perr.path, i))
+		if err == nil && valid(data) == nil {
+			le.Backup = backupName(perr.path, i)
+			break
+		}
+	}
+	log.Printf("Failed to load %s: %v", perr.path, perr.err)
+	s.loadErrors = append(s.loadErrors, le)
+}
+
+// DismissLoadError forgets a load error without restoring a backup
+func (s *Storage) DismissLoadError(file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dismissLoadError(file)
+}
+
+func (s *Storage) dismissLoadError(file string) {
+	for i, le := range s.loadErrors {
+		if le.File == file {
+			s.loadErrors = append(s.loadErrors[:i], s.loadErrors[i+1:]...)
+			return
+		}
+	}
+}
+
+// RestoreBackup replaces the data of an unreadable file with its newest readable backup
+func (s *Storage) RestoreBackup(file string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var backup string
+	for _, le := range s.loadErrors {
+		if le.File == file {
+			backup = le.Backup
+		}
+	}
+	if backup == "" {
+		return fmt.Errorf("no backup of %s to restore", filepath.Base(file))
+	}
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return err
+	}
+
+	switch file {
+	case filepath.Join(s.dataDir, templatesFile):
+		var templates []models.Template
+		if err := decodeFile(templatesFile, data, &templates); err != nil {
+			return err
+		}
+		s.templates = templates
+		err = s.saveTemplates()
+	case filepath.Join(s.dataDir, historyFile):
+		var history []models.HistoryItem
+		if err := decodeFile(historyFile, data, &history); err != nil {
+			return err
+		}
+		s.history = history
+		err = s.saveHistory()
+	case filepath.Join(s.dataDir, settingsFile):
+		settings := models.DefaultSettings()
+		if err := decodeFile(settingsFile, data, &settings); err != nil {
+			return err
+		}
+		s.settings = settings
+		err = s.saveSettings()
+	default:
+		return fmt.Errorf("no backup of %s to restore", filepath.Base(file))
+	}
+	if err != nil {
+		return err
+	}
+	s.dismissLoadError(file)
+	return nil
+}
+
+// validFile returns a check that data decodes as the named file holding a T
+func validFile[T any](name string) func([]byte) error {
+	return func(data []byte) error {
+		var v T
+		return decodeFile(name, data, &v)
+	}
+}
+
+// fileError marks decoding errors of the file at path as parse errors, which
+// are recovered from; files from newer versions are reported as they are
+func fileError(path string, err error) error {
+	var verr *VersionError
+	if errors.As(err, &verr) {
+		return err
+	}
+	return &parseError{path, err}
+}
+
+// writeFile replaces path with data so that a crash leaves either the old or
+// the new content: data is written to a temporary file in the same directory,
+// synced, and renamed over path. The previous content is kept as a backup.
+func writeFile(path string, data []byte, backups int) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	if backups > 0 {
+		if err := rotateBackups(path, backups); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// rotateBackups shifts path.bak.1 ... path.bak.N up by one and keeps the
+// current content of path as path.bak.1
+func rotateBackups(path string, backups int) error {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for i := backups - 1; i >= 1; i-- {
+		if err := os.Rename(backupName(path, i), backupName(path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// A hard link keeps path in place until the rename replaces it
+	first := backupName(path, 1)
+	os.Remove(first)
+	if err := os.Link(path, first); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(first, data, 0644)
+}
+
+// backupName returns the name of the nth backup of path
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// syncDir flushes a directory so a rename in it survives a crash. Not every
+// platform can sync directories, so failures are ignored.
+func syncDir(dir string) {
+	if f, err := os.Open(dir); err == nil {
+		f.Sync()
+		f.Close()
+	}
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"percentman/models"
+)
+
+// HistoryQuery selects history items. Zero fields match every item.
+type HistoryQuery struct {
+	// Text is searched for, ignoring case, in the URL, headers and bodies
+	Text string
+	// Method is an HTTP method, or models.RequestTypeWebSocket or
+	// models.RequestTypeGRPC to select those requests
+	Method string
+	// StatusClass selects HTTP responses by their first status digit, e.g. 4 for 4xx
+	StatusClass int
+	// Failed selects requests that got no response; ErrorKind narrows them
+	// down to one of the models.ErrorKind constants
+	Failed    bool
+	ErrorKind string
+	// Host is searched for, ignoring case, in the host of the URL
+	Host string
+	// From and To select items sent at or after From and before To
+	From, To time.Time
+
+	// Offset skips that many matching items, newest first; Limit returns at
+	// most that many, or all when 0
+	Offset, Limit int
+}
+
+// historySummary holds the fields of a history item that queries filter on,
+// other than time and text, so they can be checked without reading the item
+type historySummary struct {
+	// Type is the request type; empty for HTTP
+	Type      string `json:"type,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Host      string `json:"host,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	Failed    bool   `json:"failed,omitempty"`
+	ErrorKind string `json:"error_kind,omitempty"`
+}
+
+// summarize returns the fields of h that queries filter on
+func summarize(h *models.HistoryItem) historySummary {
+	return historySummary{
+		Type:      h.Request.Type,
+		Method:    h.Request.Method,
+		Host:      historyHost(h.Request.URL),
+		Status:    h.Response.StatusCode,
+		Failed:    h.Response.Error != "",
+		ErrorKind: h.Response.ErrorKind,
+	}
+}
+
+// Matches reports whether h is selected by the query, ignoring Offset and Limit
+func (q HistoryQuery) Matches(h *models.HistoryItem) bool {
+	if !q.From.IsZero() && h.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !h.Timestamp.Before(q.To) {
+		return false
+	}
+	return q.matchesSummary(summarize(h)) && q.matchesText(h)
+}
+
+// matchesSummary reports whether an item is selected by the filters other
+// than time and text
+func (q HistoryQuery) matchesSummary(s historySummary) bool {
+	switch q.Method {
+	case "":
+	case models.RequestTypeWebSocket, models.RequestTypeGRPC:
+		if s.Type != q.Method {
+			return false
+		}
+	default:
+		if s.Type != "" || !strings.EqualFold(s.Method, q.Method) {
+			return false
+		}
+	}
+
+	if q.StatusClass != 0 {
+		// gRPC and WebSocket have no HTTP status, and failed requests none at all
+		if s.Type != "" || s.Status/100 != q.StatusClass {
+			return false
+		}
+	}
+
+	if q.Failed && !s.Failed {
+		return false
+	}
+	if q.ErrorKind != "" && s.ErrorKind != q.ErrorKind {
+		return false
+	}
+	return q.Host == "" || containsFold(s.Host, q.Host)
+}
+
+// matchesText reports whether h holds the text searched for
+func (q HistoryQuery) matchesText(h *models.HistoryItem) bool {
+	return q.Text == "" || matchesText(h, strings.ToLower(q.Text))
+}
+
+// matchesText reports whether the URL, headers or bodies of h contain text,
+// which is lower case
+func matchesText(h *models.HistoryItem, text string) bool {
+	fields := []string{h.Request.URL, h.Request.Body, h.Response.Body}
+	for _, header := range h.Request.Headers {
+		fields = append(fields, header.Key+": "+header.Value)
+	}
+	for key, value := range h.Response.Headers {
+		fields = append(fields, key+": "+value)
+	}
+	if g := h.Request.GraphQL; g != nil {
+		fields = append(fields, g.Query, g.Variables)
+	}
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether s contains substr, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// historyHost returns the host name of a request URL, which may lack a scheme
+func historyHost(rawURL string) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// searchHistory returns the page of items, newest first, selected by q and
+// the number of items selected in total
+func searchHistory(history []models.HistoryItem, q HistoryQuery) ([]models.HistoryItem, int) {
+	items := []models.HistoryItem{}
+	total := 0
+	for i := range history {
+		if !q.Matches(&history[i]) {
+			continue
+		}
+		if total >= q.Offset && (q.Limit == 0 || len(items) < q.Limit) {
+			items = append(items, history[i])
+		}
+		total++
+	}
+	return items, total
+}
+
+// retainHistory drops the items, newest first, that the retention settings
+// no longer keep
+func retainHistory(history []models.HistoryItem, r models.HistoryRetention, now time.Time) []models.HistoryItem {
+	if r.MaxItems > 0 && len(history) > r.MaxItems {
+		history = history[:r.MaxItems]
+	}
+	if r.MaxAgeDays > 0 {
+		cutoff := historyCutoff(r, now)
+		for len(history) > 0 && history[len(history)-1].Timestamp.Before(cutoff) {
+			history = history[:len(history)-1]
+		}
+	}
+	return history
+}
+
+// historyCutoff returns the time before which items are too old to keep
+func historyCutoff(r models.HistoryRetention, now time.Time) time.Time {
+	return now.AddDate(0, 0, -r.MaxAgeDays)
+}
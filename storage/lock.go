@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// lockFile is locked while a process writes to the data directory
+	lockFile    = ".lock"
+	lockTimeout = 5 * time.Second
+	lockRetry   = 50 * time.Millisecond
+)
+
+// LockedError reports that another process kept the data directory locked
+type LockedError struct {
+	Dir string
+	// PID is the process holding the lock, or 0 if unknown
+	PID int
+}
+
+func (e *LockedError) Error() string {
+	if e.PID != 0 {
+		return fmt.Sprintf("%s is locked by another percentman instance (process %d); try again once it has finished saving", e.Dir, e.PID)
+	}
+	return fmt.Sprintf("%s is locked by another percentman instance; try again once it has finished saving", e.Dir)
+}
+
+// lockDir takes the advisory lock on the data directory, waiting up to
+// lockTimeout for other processes to release it, and returns the function
+// that releases it
+func (s *Storage) lockDir() (unlock func(), err error) {
+	f, err := os.OpenFile(filepath.Join(s.dataDir, lockFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			data, _ := os.ReadFile(f.Name())
+			pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+			f.Close()
+			return nil, &LockedError{Dir: s.dataDir, PID: pid}
+		}
+		time.Sleep(lockRetry)
+	}
+
+	// Record the holder for the message other processes show
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
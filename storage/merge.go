@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"sort"
+
+	"percentman/models"
+)
+
+// templateRevisions maps template IDs to their revisions
+func templateRevisions(templates []models.Template) map[string]int {
+	revisions := make(map[string]int, len(templates))
+	for _, t := range templates {
+		revisions[t.ID] = t.Revision
+	}
+	return revisions
+}
+
+// removedIDs returns the IDs of templates in before that are not in after
+func removedIDs(before, after []models.Template) []string {
+	kept := templateRevisions(after)
+	var removed []string
+	for _, t := range before {
+		if _, ok := kept[t.ID]; !ok {
+			removed = append(removed, t.ID)
+		}
+	}
+	return removed
+}
+
+// historyIDs returns the set of history item IDs
+func historyIDs(history []models.HistoryItem) map[string]bool {
+	ids := make(map[string]bool, len(history))
+	for _, h := range history {
+		ids[h.ID] = true
+	}
+	return ids
+}
+
+// mergeTemplates combines the templates held here (mine) with those on disk
+// (theirs), given the revisions both started from (base). Changes made on
+// only one side are kept; when both sides changed a template, the higher
+// revision wins, then the later update.
+func mergeTemplates(base map[string]int, mine, theirs []models.Template) []models.Template {
+	theirsByID := make(map[string]models.Template, len(theirs))
+	for _, t := range theirs {
+		theirsByID[t.ID] = t
+	}
+
+	result := []models.Template{}
+	seen := make(map[string]bool)
+	for _, m := range mine {
+		seen[m.ID] = true
+		baseRevision, known := base[m.ID]
+		t, onDisk := theirsByID[m.ID]
+		switch {
+		case !known:
+			// Added here
+			result = append(result, m)
+		case !onDisk:
+			// Deleted elsewhere; an edit made here since keeps it
+			if m.Revision > baseRevision {
+				result = append(result, m)
+			}
+		case m.Revision > baseRevision && t.Revision > baseRevision:
+			result = append(result, newerTemplate(m, t))
+		case m.Revision > baseRevision:
+			result = append(result, m)
+		default:
+			result = append(result, t)
+		}
+	}
+	for _, t := range theirs {
+		if seen[t.ID] {
+			continue
+		}
+		// Added elsewhere, or edited elsewhere after being deleted here
+		if baseRevision, known := base[t.ID]; !known || t.Revision > baseRevision {
+			result = append(result, t)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// newerTemplate picks between two edits of the same template
+func newerTemplate(a, b models.Template) models.Template {
+	if a.Revision != b.Revision {
+		if a.Revision > b.Revision {
+			return a
+		}
+		return b
+	}
+	if b.UpdatedAt.After(a.UpdatedAt) {
+		return b
+	}
+	return a
+}
+
+// mergeHistory combines the history held here with the history on disk,
+// given the item IDs both started from. Items added on either side are kept
+// and items removed on either side stay removed.
+func mergeHistory(base map[string]bool, mine, theirs []models.HistoryItem) []models.HistoryItem {
+	onDisk := historyIDs(theirs)
+	held := historyIDs(mine)
+
+	result := []models.HistoryItem{}
+	for _, h := range mine {
+		if onDisk[h.ID] || !base[h.ID] {
+			result = append(result, h)
+		}
+	}
+	for _, h := range theirs {
+		if !held[h.ID] && !base[h.ID] {
+			result = append(result, h)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.After(result[j].Timestamp)
+	})
+	return result
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"percentman/models"
+)
+
+// revisionsFile holds the earlier versions of templates
+const revisionsFile = "revisions.json"
+
+// maxRevisions is how many earlier versions of each template are kept
+const maxRevisions = 20
+
+// replacedTemplates compares templates before and after a change and returns
+// the earlier versions of templates whose request changed, and the IDs of
+// templates that were removed
+func replacedTemplates(before, after []models.Template) (replaced []models.Template, removed []string) {
+	current := make(map[string]*models.Template, len(after))
+	for i := range after {
+		current[after[i].ID] = &after[i]
+	}
+	for _, t := range before {
+		c, ok := current[t.ID]
+		switch {
+		case !ok:
+			removed = append(removed, t.ID)
+		case !sameJSON(t.Request, c.Request):
+			replaced = append(replaced, t)
+		}
+	}
+	return replaced, removed
+}
+
+// addRevision puts t first in the earlier versions of its template, keeping
+// at most maxRevisions of them
+func addRevision(revisions []models.TemplateRevision, t models.Template) []models.TemplateRevision {
+	r := models.TemplateRevision{
+		Revision: t.Revision,
+		Name:     t.Name,
+		Request:  *t.Request.Clone(),
+		SavedAt:  t.UpdatedAt,
+	}
+	revisions = append([]models.TemplateRevision{r}, revisions...)
+	if len(revisions) > maxRevisions {
+		revisions = revisions[:maxRevisions]
+	}
+	return revisions
+}
+
+// findRevision returns the given revision from revisions, or an error if it is no longer kept
+func findRevision(revisions []models.TemplateRevision, revision int) (*models.TemplateRevision, error) {
+	for _, r := range revisions {
+		if r.Revision == revision {
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d of the template is no longer kept", revision)
+}
+
+// readRevisions reads the earlier versions of all templates, by template ID
+func (s *Storage) readRevisions() (map[string][]models.TemplateRevision, error) {
+	revisions := make(map[string][]models.TemplateRevision)
+	path := filepath.Join(s.dataDir, revisionsFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return revisions, nil
+		}
+		return nil, err
+	}
+	if err := decodeFile(revisionsFile, data, &revisions); err != nil {
+		return nil, fileError(path, err)
+	}
+	return revisions, nil
+}
+
+// keepRevisions records the versions of templates that a change to
+// s.templates replaces, and forgets those of removed templates. It runs
+// before the change is saved, so a failed save never loses a version.
+// Callers hold s.mu.
+func (s *Storage) keepRevisions(before []models.Template) error {
+	replaced, removed := replacedTemplates(before, s.templates)
+	if len(replaced) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	// Other instances record their own saves in the same file
+	unlock, err := s.lockDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	revisions, err := s.readRevisions()
+	if err != nil {
+		return err
+	}
+	for _, t := range replaced {
+		revisions[t.ID] = addRevision(revisions[t.ID], t)
+	}
+	for _, id := range removed {
+		delete(revisions, id)
+	}
+	data, err := encodeFile(revisions)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(s.dataDir, revisionsFile), data, 0)
+}
+
+// TemplateRevisions returns the earlier versions of a template, newest first
+func (s *Storage) TemplateRevisions(id string) ([]models.TemplateRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revisions, err := s.readRevisions()
+	if err != nil {
+		return nil, err
+	}
+	return revisions[id], nil
+}
+
+// RestoreTemplate makes an earlier version of a template its current one.
+// The version it replaces is kept as a revision too.
+func (s *Storage) RestoreTemplate(id string, revision int) (*models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions, err := s.readRevisions()
+	if err != nil {
+		return nil, err
+	}
+	r, err := findRevision(revisions[id], revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reloadTemplate(id); err != nil {
+		return nil, err
+	}
+
+	before := cloneTemplates(s.templates)
+	restored, err := updateTemplate(s.templates, id, &r.Request, 0, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.keepRevisions(before); err != nil {
+		return nil, err
+	}
+	if err := s.saveTemplates(); err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
+
+// cloneTemplates returns a copy of templates that changes to them leave alone
+func cloneTemplates(templates []models.Template) []models.Template {
+	return append([]models.Template(nil), templates...)
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// schemaVersion is the version of the data file format written by this build.
+// Version 1 is the original format: the bare JSON value without an envelope.
+const schemaVersion = 2
+
+// envelope wraps the content of every data file with its schema version
+type envelope struct {
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// migration upgrades the data of one file from version n to n+1
+type migration func(data json.RawMessage) (json.RawMessage, error)
+
+// migrations holds, per data file, the migration from each version to the next.
+// A file without an entry for a version needs no change to its data.
+var migrations = map[string]map[int]migration{
+	// Version 2 only introduced the envelope
+}
+
+// VersionError reports a data file written by a newer build, which this build
+// must neither read nor overwrite
+type VersionError struct {
+	File    string
+	Version int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("%s uses schema version %d, but this percentman only understands up to version %d; please upgrade", e.File, e.Version, schemaVersion)
+}
+
+// decodeFile decodes the content of the named data file into v, upgrading
+// older schema versions on the way
+func decodeFile(name string, content []byte, v any) error {
+	version, data, err := unwrap(content)
+	if err != nil {
+		return err
+	}
+	if version > schemaVersion {
+		return &VersionError{File: name, Version: version}
+	}
+
+	if data, err = upgrade(name, version, data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// upgrade migrates the data of the named file from version to schemaVersion
+func upgrade(name string, version int, data json.RawMessage) (json.RawMessage, error) {
+	for ; version < schemaVersion; version++ {
+		if migrate, ok := migrations[name][version]; ok {
+			var err error
+			if data, err = migrate(data); err != nil {
+				return nil, fmt.Errorf("upgrading %s from version %d: %w", name, version, err)
+			}
+		}
+	}
+	return data, nil
+}
+
+// unwrap returns the schema version and data of a file's content. Content
+// without an envelope is version 1.
+func unwrap(content []byte) (int, json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &fields); err != nil {
+			return 0, nil, err
+		}
+		rawVersion, hasVersion := fields["version"]
+		data, hasData := fields["data"]
+		if hasVersion && hasData {
+			var version int
+			if err := json.Unmarshal(rawVersion, &version); err != nil {
+				return 0, nil, fmt.Errorf("schema version: %w", err)
+			}
+			return version, data, nil
+		}
+	}
+	return 1, trimmed, nil
+}
+
+// encodeFile encodes v as the content of a data file at the current schema version
+func encodeFile(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(envelope{Version: schemaVersion, Data: data}, "", "  ")
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// dataFiles are the files each fixture workspace holds
+var dataFiles = []string{settingsFile, templatesFile, historyFile}
+
+// openFixture copies the fixture workspace testdata/<name> to a temporary
+// directory and opens it
+func openFixture(t *testing.T, name string) (*Storage, string, error) {
+	t.Helper()
+	t.Setenv(homeEnv, t.TempDir())
+	dir := t.TempDir()
+	for _, file := range dataFiles {
+		data, err := os.ReadFile(filepath.Join("testdata", name, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := NewStorage(dir)
+	return s, dir, err
+}
+
+func TestLoadFixtures(t *testing.T) {
+	for _, name := range []string{"v1", "v2"} {
+		t.Run(name, func(t *testing.T) {
+			s, _, err := openFixture(t, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if errs := s.LoadErrors(); len(errs) > 0 {
+				t.Fatalf("load errors: %v", errs)
+			}
+
+			settings := s.GetSettings()
+			if settings.History.MaxItems != 50 || settings.Variables["host"] != "api.example.com" {
+				t.Errorf("settings = %+v", settings)
+			}
+			templates := s.GetTemplates()
+			if len(templates) != 1 || templates[0].Name != "List users" || templates[0].Request.URL != "https://{{host}}/users" {
+				t.Errorf("templates = %+v", templates)
+			}
+			history := s.GetHistory()
+			if len(history) != 1 || history[0].Response.StatusCode != 201 {
+				t.Errorf("history = %+v", history)
+			}
+		})
+	}
+}
+
+func TestMigrationRewritesFiles(t *testing.T) {
+	s, dir, err := openFixture(t, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading leaves the files alone; the next save of each writes the current version
+	for _, file := range dataFiles {
+		assertVersion(t, filepath.Join(dir, file), 1)
+	}
+	if err := s.SaveSettings(s.GetSettings()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RenameTemplate(s.GetTemplates()[0].ID, "All users"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ImportHistory(s.GetHistory()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range dataFiles {
+		path := filepath.Join(dir, file)
+		assertVersion(t, path, schemaVersion)
+
+		// The version 1 content is kept as the first backup
+		original, err := os.ReadFile(filepath.Join("testdata", "v1", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		backup, err := os.ReadFile(backupName(path, 1))
+		if err != nil {
+			t.Fatalf("%s: no backup: %v", file, err)
+		}
+		if !bytes.Equal(backup, original) {
+			t.Errorf("%s: backup differs from the original:\n%s", file, backup)
+		}
+	}
+
+	// The upgraded files read back
+	s, err = NewStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templates := s.GetTemplates(); len(templates) != 1 || templates[0].Name != "All users" {
+		t.Errorf("templates = %+v", templates)
+	}
+	if history := s.GetHistory(); len(history) != 2 {
+		t.Errorf("history has %d items, want 2", len(history))
+	}
+}
+
+func TestNewerVersionRefused(t *testing.T) {
+	_, dir, err := openFixture(t, "v3")
+	var verr *VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want a VersionError", err)
+	}
+	if verr.Version != 3 {
+		t.Errorf("version = %d, want 3", verr.Version)
+	}
+
+	// Nothing is moved aside, backed up or rewritten
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(dataFiles) {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("files = %v, want only %v", names, dataFiles)
+	}
+	for _, file := range dataFiles {
+		original, err := os.ReadFile(filepath.Join("testdata", "v3", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, original) {
+			t.Errorf("%s was rewritten", file)
+		}
+	}
+}
+
+// assertVersion checks the schema version of the data file at path
+func assertVersion(t *testing.T, path string, want int) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, _, err := unwrap(content)
+	if err != nil {
+		t.Fatalf("%s: %v", filepath.Base(path), err)
+	}
+	if version != want {
+		t.Errorf("%s: version %d, want %d", filepath.Base(path), version, want)
+	}
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"percentman/models"
+)
+
+// sessionFile records the requests open in the editor
+const sessionFile = "session.json"
+
+// LoadSession returns the requests that were open when the workspace in dir
+// was last used
+func LoadSession(dir string) (models.Session, error) {
+	var session models.Session
+	path := filepath.Join(dir, sessionFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return session, nil
+		}
+		return session, err
+	}
+	if err := decodeFile(sessionFile, data, &session); err != nil {
+		return models.Session{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return session, nil
+}
+
+// SaveSession records the requests open in the editor for the workspace in dir
+func SaveSession(dir string, session models.Session) error {
+	data, err := encodeFile(session)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, sessionFile), data, 0)
+}
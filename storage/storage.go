@@ -1,7 +1,7 @@
 package storage
 
 import (
-	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"sort"
@@ -9,15 +9,17 @@ import (
 	"time"
 
 	"percentman/models"
+	"percentman/redact"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/google/uuid"
 )
 
 const (
-	maxHistoryItems = 50
-	appDirName      = ".gopostman"
-	templatesFile   = "templates.json"
-	historyFile     = "history.json"
+	appDirName    = ".gopostman"
+	templatesFile = "templates.json"
+	historyFile   = "history.json"
+	settingsFile  = "settings.json"
 )
 
 // Storage handles persistence of templates and history
@@ -25,57 +27,137 @@ type Storage struct {
 	mu        sync.RWMutex
 	templates []models.Template
 	history   []models.HistoryItem
+	settings  models.Settings
 	dataDir   string
+
+	// watcher reports changes made by other programs; see Watch
+	watcher *fsnotify.Watcher
+
+	// loadErrors lists data files that could not be read when opening
+	loadErrors []LoadError
+
+	// syncedTemplates and syncedHistory describe the data as last read from or
+	// written to disk, so saves can merge changes made by other instances
+	syncedTemplates map[string]int
+	syncedHistory   map[string]bool
 }
 
-// NewStorage creates a new storage instance
-func NewStorage() (*Storage, error) {
-	homeDir, err := os.UserHomeDir()
+// NewStorage creates a storage instance for the workspace in dataDir,
+// or for the default workspace if dataDir is empty
+func NewStorage(dataDir string) (*Storage, error) {
+	dataDir, err := workspaceDir(dataDir)
 	if err != nil {
 		return nil, err
 	}
 
-	dataDir := filepath.Join(homeDir, appDirName)
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return nil, err
 	}
+	if err := rememberWorkspace(dataDir); err != nil {
+		return nil, err
+	}
 
 	s := &Storage{
 		dataDir:   dataDir,
 		templates: []models.Template{},
 		history:   []models.HistoryItem{},
+		settings:  models.DefaultSettings(),
 	}
 
-	// Load existing data
-	s.loadTemplates()
-	s.loadHistory()
+	// Load existing data; settings first, as they choose where templates live
+	if err := s.load(s.loadSettings, validFile[models.Settings](settingsFile)); err != nil {
+		return nil, err
+	}
+	if err := s.load(s.loadTemplates, validFile[[]models.Template](templatesFile)); err != nil {
+		return nil, err
+	}
+	if err := s.load(s.loadHistory, validFile[[]models.HistoryItem](historyFile)); err != nil {
+		return nil, err
+	}
 
 	return s, nil
 }
 
+// load runs a loader, moving aside each unreadable file it reports and
+// trying again, so that a damaged file is never overwritten by a later save
+func (s *Storage) load(loader func() error, valid func([]byte) error) error {
+	for {
+		err := loader()
+		var perr *parseError
+		if !errors.As(err, &perr) {
+			return err
+		}
+		s.recoverFile(perr, valid)
+		if s.loadErrors[len(s.loadErrors)-1].Kept == "" {
+			return err
+		}
+	}
+}
+
 // Templates
 
 func (s *Storage) loadTemplates() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	templates, err := s.readTemplates()
+	if err != nil {
+		return err
+	}
+	s.templates = templates
+	s.syncedTemplates = templateRevisions(templates)
+	return nil
+}
+
+// readTemplates reads the templates from the collection directory or templates.json
+func (s *Storage) readTemplates() ([]models.Template, error) {
+	if s.settings.Collection != "" {
+		return loadCollection(s.settings.Collection)
+	}
+
+	templates := []models.Template{}
 	data, err := os.ReadFile(filepath.Join(s.dataDir, templatesFile))
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return templates, nil
 		}
-		return err
+		return nil, err
 	}
-
-	return json.Unmarshal(data, &s.templates)
+	if err := decodeFile(templatesFile, data, &templates); err != nil {
+		return nil, fileError(filepath.Join(s.dataDir, templatesFile), err)
+	}
+	return templates, nil
 }
 
+// saveTemplates merges the templates with changes other instances saved
+// since they were read, then writes the result while holding the directory lock
 func (s *Storage) saveTemplates() error {
-	data, err := json.MarshalIndent(s.templates, "", "  ")
+	unlock, err := s.lockDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	disk, err := s.readTemplates()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(s.dataDir, templatesFile), data, 0644)
+	templates := mergeTemplates(s.syncedTemplates, s.templates, disk)
+
+	if s.settings.Collection != "" {
+		err = saveCollection(s.settings.Collection, templates, removedIDs(disk, templates))
+	} else {
+		var data []byte
+		if data, err = encodeFile(templates); err == nil {
+			err = writeFile(filepath.Join(s.dataDir, templatesFile), data, maxBackups)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	s.templates = templates
+	s.syncedTemplates = templateRevisions(templates)
+	return nil
 }
 
 // GetTemplates returns all templates
@@ -88,46 +170,115 @@ func (s *Storage) GetTemplates() []models.Template {
 	return result
 }
 
-// SaveTemplate saves a new template or updates existing one
+// SaveTemplate saves req as a new template called name, replacing the
+// template already called that, if any
 func (s *Storage) SaveTemplate(name string, req *models.Request) (*models.Template, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	now := time.Now()
-
-	// Check if template with same name exists
-	for i, t := range s.templates {
-		if t.Name == name {
-			s.templates[i].Request = *req.Clone()
-			s.templates[i].UpdatedAt = now
-			if err := s.saveTemplates(); err != nil {
-				return nil, err
-			}
-			return &s.templates[i], nil
-		}
+	before := cloneTemplates(s.templates)
+	var saved models.Template
+	s.templates, saved, _ = addTemplate(s.templates, name, req, time.Now())
+	if err := s.keepRevisions(before); err != nil {
+		return nil, err
 	}
+	if err := s.saveTemplates(); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
 
-	// Create new template
-	template := models.Template{
-		ID:        uuid.New().String(),
-		Name:      name,
-		Request:   *req.Clone(),
-		CreatedAt: now,
-		UpdatedAt: now,
+// UpdateTemplate replaces the request of the template with the given ID. A
+// revision other than 0 must be the current one, or ErrTemplateChanged is returned.
+func (s *Storage) UpdateTemplate(id string, req *models.Request, revision int) (*models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadTemplate(id); err != nil {
+		return nil, err
+	}
+	before := cloneTemplates(s.templates)
+	saved, err := updateTemplate(s.templates, id, req, revision, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.keepRevisions(before); err != nil {
+		return nil, err
+	}
+	if err := s.saveTemplates(); err != nil {
+		return nil, err
 	}
+	return &saved, nil
+}
 
-	s.templates = append(s.templates, template)
+// RenameTemplate renames the template with the given ID
+func (s *Storage) RenameTemplate(id, name string) (*models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Sort by name
-	sort.Slice(s.templates, func(i, j int) bool {
-		return s.templates[i].Name < s.templates[j].Name
-	})
+	if err := s.reloadTemplate(id); err != nil {
+		return nil, err
+	}
+	var renamed models.Template
+	var err error
+	if s.templates, renamed, err = renameTemplate(s.templates, id, name, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.saveTemplates(); err != nil {
+		return nil, err
+	}
+	return &renamed, nil
+}
+
+// DuplicateTemplate saves a copy of the template with the given ID
+func (s *Storage) DuplicateTemplate(id string) (*models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	var copied models.Template
+	var err error
+	if s.templates, copied, err = duplicateTemplate(s.templates, id, time.Now()); err != nil {
+		return nil, err
+	}
 	if err := s.saveTemplates(); err != nil {
 		return nil, err
 	}
+	return &copied, nil
+}
 
-	return &template, nil
+// reloadTemplate takes the version of a template that another instance saved
+// since it was read, so that saving over it is judged against that version.
+// Callers hold s.mu.
+func (s *Storage) reloadTemplate(id string) error {
+	disk, err := s.readTemplates()
+	if err != nil {
+		return err
+	}
+	t := findTemplate(disk, id)
+	if t == nil || t.Revision <= s.syncedTemplates[id] {
+		return nil
+	}
+	for i := range s.templates {
+		if s.templates[i].ID == id {
+			s.templates[i] = *t
+		}
+	}
+	s.syncedTemplates[id] = t.Revision
+	return nil
+}
+
+// ImportTemplates adds imported templates. A template whose Source matches an
+// existing template replaces that template's request instead of being added again.
+func (s *Storage) ImportTemplates(templates []models.Template) (created, updated int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := cloneTemplates(s.templates)
+	s.templates, _, created, updated = importTemplates(s.templates, templates, time.Now())
+	if err := s.keepRevisions(before); err != nil {
+		return 0, 0, err
+	}
+	return created, updated, s.saveTemplates()
 }
 
 // DeleteTemplate deletes a template by ID
@@ -135,11 +286,13 @@ func (s *Storage) DeleteTemplate(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for i, t := range s.templates {
-		if t.ID == id {
-			s.templates = append(s.templates[:i], s.templates[i+1:]...)
-			return s.saveTemplates()
+	before := cloneTemplates(s.templates)
+	var removed bool
+	if s.templates, removed = removeTemplate(s.templates, id); removed {
+		if err := s.keepRevisions(before); err != nil {
+			return err
 		}
+		return s.saveTemplates()
 	}
 	return nil
 }
@@ -149,12 +302,7 @@ func (s *Storage) GetTemplateByID(id string) *models.Template {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	for _, t := range s.templates {
-		if t.ID == id {
-			return &t
-		}
-	}
-	return nil
+	return findTemplate(s.templates, id)
 }
 
 // TemplateNameExists checks if a template name already exists
@@ -162,12 +310,7 @@ func (s *Storage) TemplateNameExists(name string) bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	for _, t := range s.templates {
-		if t.Name == name {
-			return true
-		}
-	}
-	return false
+	return hasTemplateName(s.templates, name)
 }
 
 // History
@@ -176,23 +319,56 @@ func (s *Storage) loadHistory() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	history, err := s.readHistory()
+	if err != nil {
+		return err
+	}
+	s.history = history
+	s.syncedHistory = historyIDs(history)
+	return nil
+}
+
+// readHistory reads the history from history.json
+func (s *Storage) readHistory() ([]models.HistoryItem, error) {
+	history := []models.HistoryItem{}
 	data, err := os.ReadFile(filepath.Join(s.dataDir, historyFile))
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return history, nil
 		}
-		return err
+		return nil, err
 	}
-
-	return json.Unmarshal(data, &s.history)
+	if err := decodeFile(historyFile, data, &history); err != nil {
+		return nil, fileError(filepath.Join(s.dataDir, historyFile), err)
+	}
+	return history, nil
 }
 
+// saveHistory merges the history with items other instances saved since it
+// was read, then writes the result while holding the directory lock
 func (s *Storage) saveHistory() error {
-	data, err := json.MarshalIndent(s.history, "", "  ")
+	unlock, err := s.lockDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	disk, err := s.readHistory()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(s.dataDir, historyFile), data, 0644)
+	history := retainHistory(mergeHistory(s.syncedHistory, s.history, disk), s.settings.History, time.Now())
+
+	data, err := encodeFile(history)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(s.dataDir, historyFile), data, maxBackups); err != nil {
+		return err
+	}
+	s.history = history
+	s.syncedHistory = historyIDs(history)
+	return nil
 }
 
 // GetHistory returns all history items
@@ -216,15 +392,11 @@ func (s *Storage) AddHistory(req *models.Request, resp *models.Response) (*model
 		Response:  *resp,
 		Timestamp: time.Now(),
 	}
+	redact.Item(&item, s.settings.Redaction)
 
-	// Prepend to history (newest first)
+	// Prepend to history (newest first); saving applies the retention settings
 	s.history = append([]models.HistoryItem{item}, s.history...)
 
-	// Limit history size
-	if len(s.history) > maxHistoryItems {
-		s.history = s.history[:maxHistoryItems]
-	}
-
 	if err := s.saveHistory(); err != nil {
 		return nil, err
 	}
@@ -232,6 +404,26 @@ func (s *Storage) AddHistory(req *models.Request, resp *models.Response) (*model
 	return &item, nil
 }
 
+// ImportHistory adds history items recorded elsewhere, keeping the newest first.
+// The retention settings apply to them as to requests sent here.
+func (s *Storage) ImportHistory(items []models.HistoryItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		item.ID = uuid.New().String()
+		item.Request = *item.Request.Clone()
+		redact.Item(&item, s.settings.Redaction)
+		s.history = append(s.history, item)
+	}
+
+	sort.SliceStable(s.history, func(i, j int) bool {
+		return s.history[i].Timestamp.After(s.history[j].Timestamp)
+	})
+
+	return s.saveHistory()
+}
+
 // ClearHistory removes all history items
 func (s *Storage) ClearHistory() error {
 	s.mu.Lock()
@@ -241,6 +433,15 @@ func (s *Storage) ClearHistory() error {
 	return s.saveHistory()
 }
 
+// SearchHistory returns the page of history items selected by q, newest
+// first, and how many items it selects in total
+func (s *Storage) SearchHistory(q HistoryQuery) ([]models.HistoryItem, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return searchHistory(s.history, q)
+}
+
 // GetHistoryByID returns a history item by ID
 func (s *Storage) GetHistoryByID(id string) *models.HistoryItem {
 	s.mu.RLock()
@@ -253,3 +454,111 @@ func (s *Storage) GetHistoryByID(id string) *models.HistoryItem {
 	}
 	return nil
 }
+
+// Settings
+
+func (s *Storage) loadSettings() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(s.dataDir, settingsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	settings := models.DefaultSettings()
+	if err := decodeFile(settingsFile, data, &settings); err != nil {
+		return fileError(filepath.Join(s.dataDir, settingsFile), err)
+	}
+	s.settings = settings
+	return nil
+}
+
+func (s *Storage) saveSettings() error {
+	unlock, err := s.lockDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := encodeFile(s.settings)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(s.dataDir, settingsFile), data, maxBackups)
+}
+
+// GetSettings returns the app-wide settings
+func (s *Storage) GetSettings() models.Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.settings
+}
+
+// SaveSettings replaces the app-wide settings. Changing the collection
+// directory switches where templates are kept; see useCollection.
+func (s *Storage) SaveSettings(settings models.Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if settings.Collection != s.settings.Collection {
+		if err := s.useCollection(settings.Collection); err != nil {
+			return err
+		}
+	}
+
+	retention := s.settings.History
+	s.settings = settings
+	if err := s.saveSettings(); err != nil {
+		return err
+	}
+	if settings.History != retention {
+		return s.saveHistory()
+	}
+	return nil
+}
+
+// useCollection switches template storage to a collection directory, or back
+// to templates.json when dir is empty. An existing collection is loaded;
+// otherwise the current templates are written to the new location.
+func (s *Storage) useCollection(dir string) error {
+	var templates []models.Template
+	if dir == "" {
+		data, err := os.ReadFile(filepath.Join(s.dataDir, templatesFile))
+		switch {
+		case err == nil:
+			if err := decodeFile(templatesFile, data, &templates); err != nil {
+				return err
+			}
+		case !os.IsNotExist(err):
+			return err
+		}
+	} else {
+		var err error
+		if templates, err = loadCollection(dir); err != nil {
+			return err
+		}
+	}
+
+	previous := s.settings.Collection
+	s.settings.Collection = dir
+	if len(templates) == 0 {
+		// Nothing there yet: carry the current templates over as new ones
+		synced := s.syncedTemplates
+		s.syncedTemplates = nil
+		if err := s.saveTemplates(); err != nil {
+			s.settings.Collection = previous
+			s.syncedTemplates = synced
+			return err
+		}
+	} else {
+		s.templates = templates
+		s.syncedTemplates = templateRevisions(templates)
+	}
+	s.watchDirs()
+	return nil
+}
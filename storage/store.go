@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"percentman/models"
+)
+
+// Storage backends
+const (
+	// BackendJSON keeps each kind of data in its own JSON file
+	BackendJSON = "json"
+	// BackendBolt keeps data in an embedded database, for large histories
+	BackendBolt = "bolt"
+)
+
+// boltFile is the database of a workspace using the embedded backend
+const boltFile = "percentman.db"
+
+// Store persists the templates, history and settings of one workspace
+type Store interface {
+	// Dir returns the data directory of the workspace
+	Dir() string
+	// Close releases the workspace so other processes can open it
+	Close() error
+
+	GetTemplates() []models.Template
+	SaveTemplate(name string, req *models.Request) (*models.Template, error)
+	UpdateTemplate(id string, req *models.Request, revision int) (*models.Template, error)
+	RenameTemplate(id, name string) (*models.Template, error)
+	DuplicateTemplate(id string) (*models.Template, error)
+	ImportTemplates(templates []models.Template) (created, updated int, err error)
+	DeleteTemplate(id string) error
+	GetTemplateByID(id string) *models.Template
+	TemplateNameExists(name string) bool
+	// TemplateRevisions returns the earlier versions of a template, newest first
+	TemplateRevisions(id string) ([]models.TemplateRevision, error)
+	RestoreTemplate(id string, revision int) (*models.Template, error)
+
+	GetHistory() []models.HistoryItem
+	SearchHistory(q HistoryQuery) (items []models.HistoryItem, total int)
+	AddHistory(req *models.Request, resp *models.Response) (*models.HistoryItem, error)
+	ImportHistory(items []models.HistoryItem) error
+	ClearHistory() error
+	GetHistoryByID(id string) *models.HistoryItem
+
+	GetSettings() models.Settings
+	SaveSettings(settings models.Settings) error
+
+	// Watch calls onChange when other programs change the workspace
+	Watch(onChange func(Changes)) (stop func(), err error)
+	// LoadErrors returns the data that could not be read when opening
+	LoadErrors() []LoadError
+	DismissLoadError(file string)
+	RestoreBackup(file string) error
+}
+
+// Open opens the workspace in dataDir, or the default workspace if dataDir is
+// empty, with the given backend. An empty backend picks the embedded database
+// if the workspace has one and JSON files otherwise.
+func Open(dataDir, backend string) (Store, error) {
+	if backend == "" {
+		backend = BackendJSON
+		if dir, err := workspaceDir(dataDir); err == nil {
+			if _, err := os.Stat(filepath.Join(dir, boltFile)); err == nil {
+				backend = BackendBolt
+			}
+		}
+	}
+
+	switch backend {
+	case BackendJSON:
+		s, err := NewStorage(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case BackendBolt:
+		return openBolt(dataDir)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", backend)
+}
+
+// workspaceDir returns the absolute data directory for dataDir, which may be
+// empty for the default workspace
+func workspaceDir(dataDir string) (string, error) {
+	if dataDir == "" {
+		return DefaultDir()
+	}
+	return filepath.Abs(dataDir)
+}
+
+// Close releases the workspace. The JSON backend holds no resources between saves.
+func (s *Storage) Close() error {
+	return nil
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"percentman/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrTemplateNotFound is returned for templates that do not exist, or no longer do
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrTemplateChanged is returned when a template was saved elsewhere after
+// the revision being saved over was read
+var ErrTemplateChanged = errors.New("template was changed elsewhere")
+
+// addTemplate stores req as a new template called name, replacing the
+// template of that name if there is one. It returns the templates, the new
+// template and the ID of the one it replaced, or "".
+func addTemplate(templates []models.Template, name string, req *models.Request, now time.Time) ([]models.Template, models.Template, string) {
+	var replaced string
+	for i, t := range templates {
+		if t.Name == name {
+			replaced = t.ID
+			templates = append(templates[:i:i], templates[i+1:]...)
+			break
+		}
+	}
+
+	template := models.Template{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Request:   *req.Clone(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Revision:  1,
+	}
+	templates = append(templates, template)
+	sortTemplates(templates)
+	return templates, template, replaced
+}
+
+// updateTemplate replaces the request of the template with the given ID and
+// returns the saved template. A revision other than 0 must be the stored one.
+func updateTemplate(templates []models.Template, id string, req *models.Request, revision int, now time.Time) (models.Template, error) {
+	for i, t := range templates {
+		if t.ID != id {
+			continue
+		}
+		if revision != 0 && t.Revision != revision {
+			return models.Template{}, ErrTemplateChanged
+		}
+		templates[i].Request = *req.Clone()
+		templates[i].UpdatedAt = now
+		templates[i].Revision++
+		return templates[i], nil
+	}
+	return models.Template{}, ErrTemplateNotFound
+}
+
+// renameTemplate gives the template with the given ID a name no other
+// template has, and returns the templates and the renamed template
+func renameTemplate(templates []models.Template, id, name string, now time.Time) ([]models.Template, models.Template, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return templates, models.Template{}, errors.New("template name is empty")
+	}
+	for i, t := range templates {
+		if t.ID != id {
+			continue
+		}
+		if t.Name == name {
+			return templates, t, nil
+		}
+		if hasTemplateName(templates, name) {
+			return templates, models.Template{}, fmt.Errorf("a template named %q already exists", name)
+		}
+		templates[i].Name = name
+		templates[i].UpdatedAt = now
+		templates[i].Revision++
+		renamed := templates[i]
+		sortTemplates(templates)
+		return templates, renamed, nil
+	}
+	return templates, models.Template{}, ErrTemplateNotFound
+}
+
+// duplicateTemplate adds a copy of the template with the given ID, named after
+// it, and returns the templates and the copy
+func duplicateTemplate(templates []models.Template, id string, now time.Time) ([]models.Template, models.Template, error) {
+	original := findTemplate(templates, id)
+	if original == nil {
+		return templates, models.Template{}, ErrTemplateNotFound
+	}
+
+	name := original.Name + " copy"
+	for i := 2; hasTemplateName(templates, name); i++ {
+		name = fmt.Sprintf("%s copy %d", original.Name, i)
+	}
+	// The copy is not the imported template, so re-imports leave it alone
+	template := models.Template{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Request:   *original.Request.Clone(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Folder:    original.Folder,
+		Revision:  1,
+	}
+	templates = append(templates, template)
+	sortTemplates(templates)
+	return templates, template, nil
+}
+
+// importTemplates adds imported templates. A template whose Source matches an
+// existing template replaces that template's request instead of being added
+// again. It returns the templates and the ones it created or updated.
+func importTemplates(templates, imported []models.Template, now time.Time) (result, changed []models.Template, created, updated int) {
+	bySource := make(map[string]int)
+	for i, t := range templates {
+		if t.Source != "" {
+			bySource[t.Source] = i
+		}
+	}
+
+	changedAt := make(map[int]bool)
+	for _, t := range imported {
+		if i, ok := bySource[t.Source]; ok && t.Source != "" {
+			templates[i].Name = t.Name
+			templates[i].Folder = t.Folder
+			templates[i].Request = *t.Request.Clone()
+			templates[i].UpdatedAt = now
+			templates[i].Revision++
+			changedAt[i] = true
+			updated++
+			continue
+		}
+
+		t.ID = uuid.New().String()
+		t.Request = *t.Request.Clone()
+		t.CreatedAt = now
+		t.UpdatedAt = now
+		t.Revision = 1
+		templates = append(templates, t)
+		if t.Source != "" {
+			bySource[t.Source] = len(templates) - 1
+		}
+		changedAt[len(templates)-1] = true
+		created++
+	}
+
+	for i := range changedAt {
+		changed = append(changed, templates[i])
+	}
+	sortTemplates(templates)
+	return templates, changed, created, updated
+}
+
+// removeTemplate removes the template with the given ID, reporting whether there was one
+func removeTemplate(templates []models.Template, id string) ([]models.Template, bool) {
+	for i, t := range templates {
+		if t.ID == id {
+			return append(templates[:i], templates[i+1:]...), true
+		}
+	}
+	return templates, false
+}
+
+// findTemplate returns a copy of the template with the given ID, or nil
+func findTemplate(templates []models.Template, id string) *models.Template {
+	for _, t := range templates {
+		if t.ID == id {
+			return &t
+		}
+	}
+	return nil
+}
+
+// hasTemplateName reports whether a template has the given name
+func hasTemplateName(templates []models.Template, name string) bool {
+	for _, t := range templates {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sortTemplates sorts templates by name
+func sortTemplates(templates []models.Template) {
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// vaultFile holds the encrypted secrets of a workspace
+const vaultFile = "vault.json"
+
+// scrypt parameters for deriving the vault key from a passphrase
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keySize = 32
+)
+
+// vaultCheck is sealed with the key so a wrong passphrase can be told apart
+var vaultCheck = []byte("percentman vault")
+
+var (
+	// ErrVaultLocked is returned when secrets are used before unlocking the vault
+	ErrVaultLocked = errors.New("the secrets vault is locked")
+	// ErrWrongPassphrase is returned when a passphrase does not unlock the vault
+	ErrWrongPassphrase = errors.New("wrong passphrase or key file")
+)
+
+// vaultData is the content of vault.json. Each secret is sealed with
+// AES-256-GCM under a key derived with scrypt; the nonce precedes the
+// ciphertext and the secret's name is authenticated with it.
+type vaultData struct {
+	Salt    []byte            `json:"salt"`
+	N       int               `json:"n"`
+	R       int               `json:"r"`
+	P       int               `json:"p"`
+	Check   []byte            `json:"check"`
+	Secrets map[string][]byte `json:"secrets"`
+}
+
+// Vault keeps secrets such as tokens and passwords encrypted at rest. It
+// starts locked; Unlock derives the key from the master passphrase or the
+// content of a key file.
+type Vault struct {
+	mu   sync.RWMutex
+	path string
+	data vaultData
+	// key is nil while the vault is locked
+	key cipher.AEAD
+}
+
+// OpenVault reads the vault of the workspace in dir. The vault needs no file
+// until Create is called.
+func OpenVault(dir string) (*Vault, error) {
+	v := &Vault{path: filepath.Join(dir, vaultFile)}
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return v, nil
+		}
+		return nil, err
+	}
+	if err := decodeFile(vaultFile, data, &v.data); err != nil {
+		return nil, fmt.Errorf("%s: %w", v.path, err)
+	}
+	return v, nil
+}
+
+// Created reports whether the vault has a passphrase yet
+func (v *Vault) Created() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.data.Salt != nil
+}
+
+// Locked reports whether secrets cannot be read or changed
+func (v *Vault) Locked() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.key == nil
+}
+
+// Create sets up an empty vault protected by passphrase and unlocks it
+func (v *Vault) Create(passphrase []byte) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.data.Salt != nil {
+		return errors.New("the secrets vault already exists")
+	}
+	if len(passphrase) == 0 {
+		return errors.New("passphrase is required")
+	}
+
+	data := vaultData{N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, 16), Secrets: map[string][]byte{}}
+	if _, err := rand.Read(data.Salt); err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, data)
+	if err != nil {
+		return err
+	}
+	if data.Check, err = sealValue(key, "", vaultCheck); err != nil {
+		return err
+	}
+
+	v.data = data
+	if err := v.save(); err != nil {
+		v.data = vaultData{}
+		return err
+	}
+	v.key = key
+	return nil
+}
+
+// Unlock derives the key from passphrase, which may also be the content of a key file
+func (v *Vault) Unlock(passphrase []byte) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.data.Salt == nil {
+		return errors.New("the secrets vault has not been created")
+	}
+	key, err := deriveKey(passphrase, v.data)
+	if err != nil {
+		return err
+	}
+	if _, err := openSealed(key, "", v.data.Check); err != nil {
+		return ErrWrongPassphrase
+	}
+	v.key = key
+	return nil
+}
+
+// Lock forgets the key until the next Unlock
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.key = nil
+}
+
+// Names returns the names of the stored secrets, sorted. They are readable
+// while the vault is locked.
+func (v *Vault) Names() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	names := make([]string, 0, len(v.data.Secrets))
+	for name := range v.data.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Values returns all secrets by name
+func (v *Vault) Values() (map[string]string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.key == nil {
+		return nil, ErrVaultLocked
+	}
+	values := make(map[string]string, len(v.data.Secrets))
+	for name, sealed := range v.data.Secrets {
+		value, err := openSealed(v.key, name, sealed)
+		if err != nil {
+			return nil, fmt.Errorf("secret %q: %w", name, err)
+		}
+		values[name] = string(value)
+	}
+	return values, nil
+}
+
+// Set stores a secret, replacing any secret with the same name
+func (v *Vault) Set(name, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return ErrVaultLocked
+	}
+	if name == "" {
+		return errors.New("secret name is required")
+	}
+	sealed, err := sealValue(v.key, name, []byte(value))
+	if err != nil {
+		return err
+	}
+
+	previous, existed := v.data.Secrets[name]
+	v.data.Secrets[name] = sealed
+	if err := v.save(); err != nil {
+		if existed {
+			v.data.Secrets[name] = previous
+		} else {
+			delete(v.data.Secrets, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a secret
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return ErrVaultLocked
+	}
+	previous, ok := v.data.Secrets[name]
+	if !ok {
+		return nil
+	}
+	delete(v.data.Secrets, name)
+	if err := v.save(); err != nil {
+		v.data.Secrets[name] = previous
+		return err
+	}
+	return nil
+}
+
+// save writes vault.json. Callers hold v.mu.
+func (v *Vault) save() error {
+	data, err := encodeFile(v.data)
+	if err != nil {
+		return err
+	}
+	return writeFile(v.path, data, maxBackups)
+}
+
+// deriveKey returns the cipher for the key derived from passphrase
+func deriveKey(passphrase []byte, data vaultData) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, data.Salt, data.N, data.R, data.P, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealValue encrypts plaintext, authenticating name with it
+func sealValue(key cipher.AEAD, name string, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, key.NonceSize(), key.NonceSize()+len(plaintext)+key.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return key.Seal(nonce, nonce, plaintext, []byte(name)), nil
+}
+
+// openSealed decrypts a value sealed under name
+func openSealed(key cipher.AEAD, name string, sealed []byte) ([]byte, error) {
+	if len(sealed) < key.NonceSize() {
+		return nil, errors.New("sealed value is too short")
+	}
+	nonce, ciphertext := sealed[:key.NonceSize()], sealed[key.NonceSize():]
+	return key.Open(nil, nonce, ciphertext, []byte(name))
+}
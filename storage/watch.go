@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"percentman/models"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay groups the burst of events a single save produces into one reload
+const reloadDelay = 200 * time.Millisecond
+
+// Changes reports which data a reload found changed on disk
+type Changes struct {
+	Templates bool
+	History   bool
+}
+
+// Reload re-reads templates and history from disk and reports which of them
+// differ from what is held in memory. Files this Storage wrote itself read
+// back unchanged, so its own saves are not reported.
+func (s *Storage) Reload() (Changes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changes Changes
+	templates, err := s.readTemplates()
+	if err != nil {
+		return changes, err
+	}
+	if !sameJSON(withoutTimes(templates), withoutTimes(s.templates)) {
+		s.templates = templates
+		changes.Templates = true
+	}
+	s.syncedTemplates = templateRevisions(templates)
+
+	history, err := s.readHistory()
+	if err != nil {
+		return changes, err
+	}
+	if !sameJSON(history, s.history) {
+		s.history = history
+		changes.History = true
+	}
+	s.syncedHistory = historyIDs(history)
+	return changes, nil
+}
+
+// Watch reloads templates and history whenever files in the data directory or
+// collection directory change, and calls onChange from a background goroutine
+// when something changed. Call the returned function to stop watching.
+func (s *Storage) Watch(onChange func(Changes)) (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.watcher = watcher
+	s.watchDirs()
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go runWatcher(watcher, s.dataDir, s.Reload, onChange, done)
+
+	return func() {
+		close(done)
+		watcher.Close()
+		s.mu.Lock()
+		s.watcher = nil
+		s.mu.Unlock()
+	}, nil
+}
+
+// runWatcher calls reload shortly after watcher reports changes, and onChange
+// when the reload found data changed, until done is closed
+func runWatcher(watcher *fsnotify.Watcher, name string, reload func() (Changes, error), onChange func(Changes), done chan struct{}) {
+	timer := time.AfterFunc(time.Hour, func() {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		changes, err := reload()
+		if err != nil {
+			log.Printf("Failed to reload %s: %v", name, err)
+			return
+		}
+		if changes.Templates || changes.History {
+			onChange(changes)
+		}
+	})
+	timer.Stop()
+
+	for {
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// Lock, temporary and other hidden files never hold data
+			if strings.HasPrefix(filepath.Base(event.Name), ".") {
+				continue
+			}
+			// Folders added to a collection need watching too
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+					watcher.Add(event.Name)
+				}
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Watching %s: %v", name, err)
+		}
+	}
+}
+
+// watchDirs adds the data directory and the collection directory with its
+// folders to the watcher, if one is running. Callers hold s.mu.
+func (s *Storage) watchDirs() {
+	if s.watcher == nil {
+		return
+	}
+	addDirs(s.watcher, append([]string{s.dataDir}, collectionDirs(s.settings.Collection)...))
+}
+
+// collectionDirs returns a collection directory and its folders, or nothing
+// when templates are not kept in a collection
+func collectionDirs(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	dirs := []string{dir}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+				dirs = append(dirs, filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	return dirs
+}
+
+// addDirs adds dirs to watcher
+func addDirs(watcher *fsnotify.Watcher, dirs []string) {
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			log.Printf("Failed to watch %s: %v", dir, err)
+		}
+	}
+}
+
+// withoutTimes returns copies of templates without timestamps, which
+// collection directories derive from file modification times, and with
+// missing header lists made empty as collection files read them back
+func withoutTimes(templates []models.Template) []models.Template {
+	result := make([]models.Template, len(templates))
+	for i, t := range templates {
+		t.CreatedAt = time.Time{}
+		t.UpdatedAt = time.Time{}
+		if t.Request.Headers == nil {
+			t.Request.Headers = []models.Header{}
+		}
+		result[i] = t
+	}
+	return result
+}
+
+// sameJSON reports whether a and b encode to the same JSON
+func sameJSON(a, b any) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(x) == string(y)
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	// homeEnv overrides the default data directory, for tests and portable installs
+	homeEnv        = "PERCENTMAN_HOME"
+	workspacesFile = "workspaces.json"
+)
+
+// DefaultDir returns the data directory used when no workspace is given:
+// $PERCENTMAN_HOME if set, otherwise ~/.gopostman
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(homeEnv); dir != "" {
+		return filepath.Abs(dir)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, appDirName), nil
+}
+
+// Workspaces returns the data directories opened so far, the default one first
+func Workspaces() ([]string, error) {
+	defaultDir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	data, err := os.ReadFile(filepath.Join(defaultDir, workspacesFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := decodeFile(workspacesFile, data, &dirs); err != nil {
+			return nil, err
+		}
+	}
+	return append([]string{defaultDir}, dirs...), nil
+}
+
+// rememberWorkspace records dir in the workspace list kept in the default directory
+func rememberWorkspace(dir string) error {
+	dirs, err := Workspaces()
+	if err != nil {
+		return err
+	}
+	for _, d := range dirs {
+		if d == dir {
+			return nil
+		}
+	}
+
+	data, err := encodeFile(append(dirs[1:], dir))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dirs[0], 0755); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dirs[0], workspacesFile), data, 0)
+}
+
+// Dir returns the data directory of the workspace
+func (s *Storage) Dir() string {
+	return s.dataDir
+}
@@ -1,14 +1,28 @@
 package ui
 
 import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	fynestorage "fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
+	grpcclient "percentman/grpc"
+	"percentman/har"
 	httpclient "percentman/http"
 	"percentman/models"
+	"percentman/openapi"
 	"percentman/storage"
 )
 
@@ -16,36 +30,79 @@ import (
 type App struct {
 	fyneApp    fyne.App
 	window     fyne.Window
-	storage    *storage.Storage
+	storage    storage.Store
+	vault      *storage.Vault
 	httpClient *httpclient.Client
+	grpcClient *grpcclient.Client
+
+	// spec validates responses when an OpenAPI document is loaded
+	spec *openapi.Document
+
+	// Open requests; tab is the one shown in the editor
+	tabs   []*requestTab
+	tab    *requestTab
+	tabBar *container.DocTabs
+
+	// stopWatch stops following changes to the workspace on disk
+	stopWatch func()
 
-	// Current request state
-	currentRequest *models.Request
+	// WebSocket session state; cancelWebSocket is set while connecting or connected
+	wsConn          *httpclient.WebSocketConn
+	cancelWebSocket context.CancelFunc
+	wsSession       int
+
+	// workspaceSelect switches between data directories
+	workspaceSelect *widget.Select
 
 	// UI Components
-	sidebar  *Sidebar
-	request  *RequestPanel
-	response *ResponsePanel
+	sidebar   *Sidebar
+	request   *RequestPanel
+	response  *ResponsePanel
+	websocket *WebSocketPanel
+
+	responseView  fyne.CanvasObject
+	websocketView fyne.CanvasObject
 }
 
 // NewApp creates a new application instance
-func NewApp(fyneApp fyne.App, window fyne.Window, store *storage.Storage) *App {
+func NewApp(fyneApp fyne.App, window fyne.Window, store storage.Store, vault *storage.Vault) *App {
 	app := &App{
-		fyneApp:        fyneApp,
-		window:         window,
-		storage:        store,
-		httpClient:     httpclient.NewClient(),
-		currentRequest: models.NewRequest(),
+		fyneApp:    fyneApp,
+		window:     window,
+		storage:    store,
+		vault:      vault,
+		httpClient: httpclient.NewClient(),
+		grpcClient: grpcclient.NewClient(),
 	}
+	app.applyStoredSettings()
 
 	// Initialize UI components
 	app.sidebar = NewSidebar(app)
 	app.request = NewRequestPanel(app)
 	app.response = NewResponsePanel(app)
+	app.websocket = NewWebSocketPanel(app)
+
+	app.watchStorage()
 
 	return app
 }
 
+// applyStoredSettings applies the workspace settings to the clients and loads
+// the OpenAPI document responses are validated against
+func (a *App) applyStoredSettings() {
+	settings := a.storage.GetSettings()
+	a.httpClient.SetDefaults(settings.Defaults)
+
+	a.spec = nil
+	if path := settings.Spec; path != "" {
+		if spec, err := openapi.ParseFile(path); err == nil {
+			a.spec = spec
+		} else {
+			log.Printf("Failed to load OpenAPI document %s: %v", path, err)
+		}
+	}
+}
+
 // BuildUI constructs the main UI layout
 func (a *App) BuildUI() fyne.CanvasObject {
 	// Theme selector (top-right)
@@ -63,10 +120,24 @@ func (a *App) BuildUI() fyne.CanvasObject {
 	themeSelect.PlaceHolder = "Theme"
 
 	themeLabel := widget.NewLabelWithStyle("Theme:", fyne.TextAlignTrailing, fyne.TextStyle{})
+	settingsBtn := widget.NewButtonWithIcon("Settings", theme.SettingsIcon(), func() {
+		a.ShowSettingsDialog()
+	})
+	codeBtn := widget.NewButtonWithIcon("Generate Code", theme.DocumentIcon(), func() {
+		a.ShowCodeDialog()
+	})
+	secretsBtn := widget.NewButtonWithIcon("Secrets", theme.VisibilityOffIcon(), func() {
+		a.ShowVaultDialog()
+	})
 	themeBar := container.NewHBox(
+		widget.NewLabel("Workspace:"),
+		a.buildWorkspaceSelect(),
 		layout.NewSpacer(),
 		themeLabel,
 		themeSelect,
+		codeBtn,
+		secretsBtn,
+		settingsBtn,
 	)
 
 	// Left sidebar (templates + history)
@@ -74,66 +145,522 @@ func (a *App) BuildUI() fyne.CanvasObject {
 
 	// Right side: Request panel (top) + Response panel (bottom)
 	requestPanel := a.request.Build()
-	responsePanel := a.response.Build()
+	a.responseView = a.response.Build()
+	a.websocketView = a.websocket.Build()
+	a.websocketView.Hide()
+
+	// The bottom half shows the response, or the message log for WebSocket sessions
+	responsePanel := container.NewStack(a.responseView, a.websocketView)
 
 	// Split request and response vertically (50:50)
 	rightSide := container.NewVSplit(requestPanel, responsePanel)
 	rightSide.SetOffset(0.5)
 
-	// Right side with theme bar on top
-	rightWithTheme := container.NewBorder(themeBar, nil, nil, nil, rightSide)
+	// Right side with theme bar and the open requests on top
+	tabBar := a.buildTabBar()
+	rightWithTheme := container.NewBorder(container.NewVBox(themeBar, tabBar), nil, nil, nil, rightSide)
 
 	// Main layout: sidebar (left) + main content (right)
 	mainSplit := container.NewHSplit(sidebar, rightWithTheme)
 	mainSplit.SetOffset(0.25) // 25% for sidebar
 
+	a.window.SetMainMenu(a.buildMainMenu())
+	a.showLoadErrors()
+
 	return mainSplit
 }
 
 // SendRequest executes the current HTTP request
 func (a *App) SendRequest() {
 	// Update request from UI
-	a.request.UpdateRequest(a.currentRequest)
+	a.syncRequest()
+
+	// Secrets can only be sent once the vault is unlocked
+	if a.tab.request.UsesSecrets() && a.vault.Locked() {
+		a.unlockVault(a.SendRequest)
+		return
+	}
+
+	if a.tab.request.IsWebSocket() {
+		a.connectWebSocket()
+		return
+	}
+
+	if a.tab.request.IsGRPC() {
+		a.startCall()
+		return
+	}
+
+	if a.tab.request.Stream {
+		a.startStream()
+		return
+	}
 
 	// Send request
-	resp := a.httpClient.SendRequest(a.currentRequest)
+	req := a.resolveRequest(a.tab.request)
+	resp := a.httpClient.SendRequest(req)
 
 	// Display response
+	a.tab.response = resp
 	a.response.DisplayResponse(resp)
 
-	// Save to history (only if no error)
-	if resp.Error == "" {
-		a.storage.AddHistory(a.currentRequest, resp)
-		a.sidebar.RefreshHistory()
+	// Check the response against the loaded OpenAPI document
+	if a.spec != nil && resp.Error == "" {
+		a.response.ShowValidation(a.spec.Validate(req, resp))
+	}
+
+	// Failed requests are saved too, with the kind of error, for investigating later
+	a.addHistory(a.tab.request, resp)
+}
+
+// IsStreaming reports whether the current tab has an event stream open
+func (a *App) IsStreaming() bool {
+	return a.tab != nil && a.tab.cancelStream != nil
+}
+
+// StopStream closes the event stream of the current tab
+func (a *App) StopStream() {
+	if a.IsStreaming() {
+		a.tab.cancelStream()
+	}
+}
+
+// startStream opens the current request as a Server-Sent Events stream
+func (a *App) startStream() {
+	ctx, cancel := context.WithCancel(context.Background())
+	tab := a.tab
+	tab.cancelStream = cancel
+	req := tab.request.Clone()
+	a.request.SetActive(true)
+	a.response.StartStream()
+
+	var first *models.Response
+	// failure is why the stream could not connect, while it never did
+	var failure error
+	// events are kept for the transcript, even once another tab is shown
+	var events []models.ServerEvent
+	handler := httpclient.StreamHandler{
+		OnConnect: func(resp *models.Response) {
+			fyne.Do(func() {
+				if first == nil {
+					first = resp
+				}
+				if tab == a.tab {
+					a.response.ShowStreamConnect(resp)
+				}
+			})
+		},
+		OnEvent: func(e models.ServerEvent) {
+			fyne.Do(func() {
+				events = append(events, e)
+				if tab == a.tab {
+					a.response.AppendEvent(e)
+				}
+			})
+		},
+		OnError: func(err error) {
+			fyne.Do(func() {
+				if first == nil {
+					failure = err
+				}
+				if tab == a.tab {
+					a.response.ShowStreamError(err, true)
+				}
+			})
+		},
+	}
+
+	go func() {
+		err := a.httpClient.StreamEvents(ctx, a.resolveRequest(req), handler)
+		fyne.Do(func() {
+			cancel()
+			tab.cancelStream = nil
+			if tab == a.tab {
+				a.request.SetActive(false)
+				if err != nil {
+					a.response.ShowStreamError(err, false)
+				}
+				a.response.EndStream()
+			}
+
+			// Save the transcript of a successful stream to history
+			if first != nil && first.StatusCode == 200 {
+				resp := *first
+				var transcript strings.Builder
+				for _, e := range events {
+					transcript.WriteString(httpclient.FormatEvent(e))
+				}
+				resp.Body = transcript.String()
+				tab.response = &resp
+				a.addHistory(req, &resp)
+			}
+
+			// Save refused streams with the status and body the server answered with
+			if first != nil && first.StatusCode != 200 {
+				tab.response = first
+				a.addHistory(req, first)
+			}
+
+			// Save streams that never connected as failed requests
+			if first == nil && err != nil {
+				failure = err
+			}
+			if first == nil && failure != nil {
+				resp := &models.Response{
+					Error:     failure.Error(),
+					ErrorKind: httpclient.ErrorKind(failure),
+				}
+				tab.response = resp
+				a.addHistory(req, resp)
+			}
+		})
+	}()
+}
+
+// IsCalling reports whether the current tab has a gRPC call running
+func (a *App) IsCalling() bool {
+	return a.tab != nil && a.tab.cancelCall != nil
+}
+
+// CancelCall aborts the gRPC call of the current tab
+func (a *App) CancelCall() {
+	if a.IsCalling() {
+		a.tab.cancelCall()
+	}
+}
+
+// callContext returns a context bounded by the request's effective timeout
+func (a *App) callContext(req *models.Request) (context.Context, context.CancelFunc) {
+	timeout := req.EffectiveSettings(a.httpClient.Defaults()).Timeout
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// startCall invokes the current request as a gRPC call
+func (a *App) startCall() {
+	tab := a.tab
+	req := tab.request.Clone()
+	ctx, cancel := a.callContext(req)
+	tab.cancelCall = cancel
+
+	a.request.SetActive(true)
+	a.response.StartCall()
+
+	// Server-streaming responses are shown as they arrive
+	onMessage := func(text string) {
+		fyne.Do(func() {
+			if tab == a.tab {
+				a.response.AppendCallMessage(text)
+			}
+		})
+	}
+
+	go func() {
+		resp := a.grpcClient.Invoke(ctx, a.resolveRequest(req), onMessage)
+		fyne.Do(func() {
+			cancel()
+			tab.cancelCall = nil
+			tab.response = resp
+			if tab == a.tab {
+				a.request.SetActive(false)
+				a.response.EndCall(resp)
+			}
+
+			// Calls that failed with a status or never reached the server are saved too
+			a.addHistory(req, resp)
+		})
+	}()
+}
+
+// resolveRequest returns a copy of req with the app variables and, while the
+// vault is unlocked, secrets substituted
+func (a *App) resolveRequest(req *models.Request) *models.Request {
+	return req.Expand(a.variables(true))
+}
+
+// syncRequest copies the editor state into the current request
+func (a *App) syncRequest() {
+	a.request.UpdateRequest(a.tab.request)
+	if a.tab.request.IsWebSocket() {
+		a.tab.request.Body = a.websocket.Draft()
+	}
+}
+
+// onRequestTypeChanged swaps the bottom panel when the request type changes
+func (a *App) onRequestTypeChanged(websocket bool) {
+	// Loading another tab keeps the streams and calls of that tab going
+	if !a.request.loading {
+		a.StopStream()
+		a.CancelCall()
+	}
+	a.DisconnectWebSocket()
+
+	// Carry the body over between the body editor and the message composer
+	if websocket {
+		a.websocket.SetDraft(a.request.bodyEntry.Text)
+		a.responseView.Hide()
+		a.websocketView.Show()
+	} else if a.websocketView.Visible() {
+		a.request.bodyEntry.SetText(a.websocket.Draft())
+		a.websocketView.Hide()
+		a.responseView.Show()
+	}
+}
+
+// IsWebSocketActive reports whether a WebSocket is connecting or connected
+func (a *App) IsWebSocketActive() bool {
+	return a.cancelWebSocket != nil
+}
+
+// connectWebSocket opens a WebSocket session for the current request
+func (a *App) connectWebSocket() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancelWebSocket = cancel
+	a.wsSession++
+	session := a.wsSession
+
+	// History keeps the request as written, so secrets are not saved in it
+	req := a.tab.request.Clone()
+	a.request.SetActive(true)
+	a.websocket.SetConnecting()
+
+	onMessage := func(m models.WebSocketMessage) {
+		fyne.Do(func() {
+			if session == a.wsSession {
+				a.websocket.AppendMessage(m)
+			}
+		})
+	}
+
+	go func() {
+		conn, resp, err := a.httpClient.DialWebSocket(ctx, a.resolveRequest(req), onMessage)
+		if err != nil {
+			// Failed handshakes are saved like failed requests, with the
+			// server's response if it sent one
+			failed := &models.Response{}
+			if resp != nil {
+				failed = resp
+			}
+			failed.Error = err.Error()
+			failed.ErrorKind = httpclient.ErrorKind(err)
+			fyne.Do(func() {
+				a.addHistory(req, failed)
+				a.endWebSocket(session, err)
+			})
+			return
+		}
+
+		fyne.Do(func() {
+			a.addHistory(req, resp)
+			// The session may have been abandoned while connecting
+			if ctx.Err() != nil {
+				go conn.Close(1000, "")
+				return
+			}
+			a.wsConn = conn
+			a.websocket.ShowConnected(resp, conn.Subprotocol())
+		})
+
+		<-conn.Done()
+		fyne.Do(func() {
+			a.endWebSocket(session, nil)
+		})
+	}()
+}
+
+// endWebSocket resets the session state once a connection attempt or session ends
+func (a *App) endWebSocket(session int, err error) {
+	// Ignore sessions that were already replaced by a newer one
+	if session != a.wsSession || a.cancelWebSocket == nil {
+		return
+	}
+
+	a.cancelWebSocket()
+	a.cancelWebSocket = nil
+	a.wsConn = nil
+	a.request.SetActive(false)
+
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	a.websocket.ShowDisconnected(err)
+}
+
+// DisconnectWebSocket closes the WebSocket session normally
+func (a *App) DisconnectWebSocket() {
+	a.CloseWebSocket(1000, "")
+}
+
+// CloseWebSocket closes the WebSocket session with the given close code
+func (a *App) CloseWebSocket(code int, reason string) {
+	if a.wsConn == nil {
+		if a.cancelWebSocket != nil {
+			a.cancelWebSocket()
+		}
+		return
 	}
+
+	// Close waits for the server's answer, so keep it off the UI thread
+	conn := a.wsConn
+	go conn.Close(code, reason)
 }
 
-// LoadRequest loads a request into the UI
+// SendWebSocketMessage sends a message of the given composer kind
+func (a *App) SendWebSocketMessage(kind, text string) error {
+	if a.wsConn == nil {
+		return errors.New("WebSocket is not connected")
+	}
+
+	switch kind {
+	case messageKindJSON:
+		if !json.Valid([]byte(text)) {
+			return errors.New("message is not valid JSON")
+		}
+		return a.wsConn.SendText(text)
+	case messageKindBinary:
+		data, err := hex.DecodeString(strings.Join(strings.Fields(text), ""))
+		if err != nil {
+			return errors.New("binary messages must be hex encoded")
+		}
+		return a.wsConn.SendBinary(data)
+	default:
+		return a.wsConn.SendText(text)
+	}
+}
+
+// PingWebSocket sends a ping frame
+func (a *App) PingWebSocket() error {
+	if a.wsConn == nil {
+		return errors.New("WebSocket is not connected")
+	}
+	return a.wsConn.Ping("")
+}
+
+// LoadRequest opens a request in a new tab, or in the current tab if it is blank
 func (a *App) LoadRequest(req *models.Request) {
-	a.currentRequest = req.Clone()
-	a.request.LoadRequest(a.currentRequest)
-	a.response.Clear()
+	a.openTab(req, nil)
 }
 
 // SaveTemplate saves the current request as a template
 func (a *App) SaveTemplate(name string) error {
-	a.request.UpdateRequest(a.currentRequest)
-	_, err := a.storage.SaveTemplate(name, a.currentRequest)
-	if err == nil {
-		a.sidebar.RefreshTemplates()
+	a.syncRequest()
+	return a.saveTabNamed(a.tab, name)
+}
+
+// saveTabNamed saves the request of tab as a new template called name, which
+// becomes the tab's template
+func (a *App) saveTabNamed(tab *requestTab, name string) error {
+	t, err := a.storage.SaveTemplate(name, tab.request)
+	if err != nil {
+		return err
 	}
-	return err
+	a.templateSaved(tab, t)
+	return nil
+}
+
+// ImportOpenAPI creates templates from an OpenAPI or Swagger document read from path.
+// The document's server becomes the baseUrl variable unless one is already set,
+// and later responses are validated against the document.
+func (a *App) ImportOpenAPI(path string, data []byte) (*openapi.Result, int, int, error) {
+	spec, err := openapi.Parse(data)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	result, err := spec.Import()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	created, updated, err := a.storage.ImportTemplates(result.Templates)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	a.sidebar.RefreshTemplates()
+
+	settings := a.storage.GetSettings()
+	if _, ok := settings.Variables[openapi.BaseURLVariable]; !ok && result.BaseURL != "" {
+		vars := make(map[string]string, len(settings.Variables)+1)
+		for k, v := range settings.Variables {
+			vars[k] = v
+		}
+		vars[openapi.BaseURLVariable] = result.BaseURL
+		settings.Variables = vars
+	}
+	settings.Spec = path
+	if err := a.SaveSettings(settings); err != nil {
+		return nil, 0, 0, err
+	}
+	a.spec = spec
+	return result, created, updated, nil
+}
+
+// ShowImportDialog asks for an OpenAPI, Swagger, HAR or .http file and imports it
+func (a *App) ShowImportDialog() {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		switch reader.URI().Extension() {
+		case ".http", ".rest":
+			a.importHTTPFile(reader.URI().Name(), data)
+			return
+		}
+		// HAR files are recognised by extension or by their log object
+		if _, err := har.Parse(data); err == nil || reader.URI().Extension() == ".har" {
+			a.importHAR(reader.URI().Name(), data)
+			return
+		}
+		result, created, updated, err := a.ImportOpenAPI(reader.URI().Path(), data)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("import %s: %w", reader.URI().Name(), err), a.window)
+			return
+		}
+		dialog.ShowInformation("Import complete",
+			fmt.Sprintf("%s: %d operations imported (%d new, %d updated)", result.Title, len(result.Templates), created, updated),
+			a.window)
+	}, a.window)
+	d.SetFilter(fynestorage.NewExtensionFileFilter([]string{".json", ".yaml", ".yml", ".har", ".http", ".rest"}))
+	d.Show()
 }
 
 // DeleteTemplate deletes a template
 func (a *App) DeleteTemplate(id string) error {
 	err := a.storage.DeleteTemplate(id)
 	if err == nil {
+		// Tabs keep the request, which becomes unsaved
+		for _, tab := range a.tabs {
+			if tab.template != nil && tab.template.ID == id {
+				tab.template = nil
+				a.refreshTab(tab)
+			}
+		}
 		a.sidebar.RefreshTemplates()
 	}
 	return err
 }
 
+// SaveSettings saves app-wide settings and applies them to the HTTP client
+func (a *App) SaveSettings(settings models.Settings) error {
+	if err := a.storage.SaveSettings(settings); err != nil {
+		return err
+	}
+	a.httpClient.SetDefaults(settings.Defaults)
+	a.request.RefreshDefaults()
+	a.sidebar.RefreshTemplates()
+	// Retention changes may have removed history
+	a.sidebar.RefreshHistory()
+	return nil
+}
+
 // ClearHistory clears all history
 func (a *App) ClearHistory() error {
 	err := a.storage.ClearHistory()
@@ -144,7 +671,7 @@ func (a *App) ClearHistory() error {
 }
 
 // GetStorage returns the storage instance
-func (a *App) GetStorage() *storage.Storage {
+func (a *App) GetStorage() storage.Store {
 	return a.storage
 }
 
@@ -153,22 +680,20 @@ func (a *App) GetWindow() fyne.Window {
 	return a.window
 }
 
-// ShowSaveTemplateDialog shows a dialog to save the current request as a template
-func (a *App) ShowSaveTemplateDialog() {
-	entry := widget.NewEntry()
-	entry.SetPlaceHolder("Enter template name")
-
-	showSaveDialog(a.window, entry, func(name string) {
-		if name != "" {
-			a.SaveTemplate(name)
-		}
-	})
+// ShowSaveAsDialog asks for a name and saves the current request as a new template
+func (a *App) ShowSaveAsDialog() {
+	a.syncRequest()
+	name := ""
+	if a.tab.template != nil {
+		name = a.tab.template.Name + " copy"
+	}
+	a.saveTabAs(a.tab, name, nil)
 }
 
-func showSaveDialog(window fyne.Window, entry *widget.Entry, onSave func(string)) {
+func showSaveDialog(window fyne.Window, title string, entry *widget.Entry, onSave func(string)) {
 	var popup *widget.PopUp
 
-	titleLabel := widget.NewLabelWithStyle("Save as Template", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	titleLabel := widget.NewLabelWithStyle(title, fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
 
 	nameLabel := widget.NewLabel("Template Name:")
 
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/codegen"
+)
+
+// ShowCodeDialog shows snippets that send the current request from other languages
+func (a *App) ShowCodeDialog() {
+	a.syncRequest()
+	// Secrets stay placeholders so snippets can be shared
+	req := a.tab.request.Expand(a.variables(false))
+
+	var popup *widget.PopUp
+
+	titleLabel := widget.NewLabelWithStyle("Generate Code", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
+	codeEntry := widget.NewMultiLineEntry()
+	codeEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	codeEntry.Wrapping = fyne.TextWrapOff
+
+	errorLabel := widget.NewLabel("")
+	errorLabel.Importance = widget.DangerImportance
+	errorLabel.Hide()
+
+	languageSelect := widget.NewSelect(codegen.Languages(), func(language string) {
+		code, err := codegen.Generate(language, req)
+		if err != nil {
+			errorLabel.SetText(err.Error())
+			errorLabel.Show()
+			codeEntry.SetText("")
+			return
+		}
+		errorLabel.Hide()
+		codeEntry.SetText(code)
+	})
+
+	copyBtn := widget.NewButton("Copy", func() {
+		a.fyneApp.Clipboard().SetContent(codeEntry.Text)
+	})
+	copyBtn.Importance = widget.HighImportance
+
+	closeBtn := widget.NewButton("Close", func() {
+		popup.Hide()
+	})
+
+	buttons := container.NewHBox(
+		layout.NewSpacer(),
+		closeBtn,
+		copyBtn,
+	)
+
+	content := container.NewBorder(
+		container.NewVBox(titleLabel, widget.NewSeparator(), languageSelect, errorLabel),
+		container.NewVBox(widget.NewSeparator(), buttons),
+		nil, nil,
+		codeEntry,
+	)
+
+	languageSelect.SetSelected(codegen.Languages()[0])
+
+	popup = widget.NewModalPopUp(container.NewPadded(content), a.window.Canvas())
+	popup.Resize(fyne.NewSize(720, 480))
+	popup.Show()
+}
@@ -0,0 +1,363 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	httpclient "percentman/http"
+	"percentman/models"
+)
+
+// maxSuggestions limits how many completions are listed below the query editor
+const maxSuggestions = 8
+
+// graphQLEditor edits the GraphQL body of a request
+type graphQLEditor struct {
+	app *App
+
+	queryEntry      *widget.Entry
+	variablesEntry  *widget.Entry
+	operationSelect *widget.Select
+
+	schema       *httpclient.GraphQLSchema
+	schemaTree   *widget.Tree
+	schemaStatus *widget.Label
+
+	suggestPrefix string
+	suggestions   []httpclient.GraphQLField
+	suggestList   *widget.List
+	suggestBox    *fyne.Container
+}
+
+// newGraphQLEditor creates a new GraphQL editor
+func newGraphQLEditor(app *App) *graphQLEditor {
+	return &graphQLEditor{
+		app: app,
+	}
+}
+
+// Build creates the GraphQL editor UI
+func (g *graphQLEditor) Build() fyne.CanvasObject {
+	// Query editor with completions below it
+	g.queryEntry = widget.NewMultiLineEntry()
+	g.queryEntry.SetPlaceHolder("query {\n  ...\n}")
+	g.queryEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	g.queryEntry.OnChanged = func(string) {
+		g.refreshOperations()
+		g.refreshSuggestions()
+		g.app.request.edited()
+	}
+	g.queryEntry.OnCursorChanged = g.refreshSuggestions
+
+	g.suggestList = widget.NewList(
+		func() int {
+			return len(g.suggestions)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			f := g.suggestions[id]
+			obj.(*widget.Label).SetText(f.Name + ": " + f.Type)
+		},
+	)
+	g.suggestList.OnSelected = func(id widget.ListItemID) {
+		g.suggestList.Unselect(id)
+		g.insertAtCursor(g.suggestPrefix, g.suggestions[id].Name)
+	}
+	suggestScroll := container.NewVScroll(g.suggestList)
+	suggestScroll.SetMinSize(fyne.NewSize(0, 120))
+	g.suggestBox = container.NewStack(suggestScroll)
+	g.suggestBox.Hide()
+
+	querySection := container.NewBorder(
+		widget.NewLabelWithStyle("Query", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		g.suggestBox, nil, nil,
+		g.queryEntry,
+	)
+
+	// Variables editor
+	g.variablesEntry = widget.NewMultiLineEntry()
+	g.variablesEntry.SetPlaceHolder(`{"id": 1}`)
+	g.variablesEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	g.variablesEntry.OnChanged = func(string) { g.app.request.edited() }
+
+	variablesSection := container.NewBorder(
+		widget.NewLabelWithStyle("Variables", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		nil, nil, nil,
+		g.variablesEntry,
+	)
+
+	editors := container.NewVSplit(querySection, variablesSection)
+	editors.SetOffset(0.65)
+
+	// Operation selector and schema controls
+	g.operationSelect = widget.NewSelect(nil, func(string) { g.app.request.edited() })
+	g.operationSelect.PlaceHolder = "(default operation)"
+
+	fetchBtn := widget.NewButtonWithIcon("Fetch Schema", theme.DownloadIcon(), func() {
+		g.fetchSchema()
+	})
+
+	g.schemaStatus = widget.NewLabel("No schema loaded")
+	g.schemaStatus.Importance = widget.LowImportance
+
+	toolbar := container.NewHBox(
+		widget.NewLabel("Operation:"),
+		g.operationSelect,
+		fetchBtn,
+		g.schemaStatus,
+	)
+
+	// Schema browser
+	g.schemaTree = widget.NewTree(
+		g.treeChildren,
+		g.treeIsBranch,
+		func(bool) fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TreeNodeID, _ bool, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(g.treeLabel(id))
+		},
+	)
+	g.schemaTree.OnSelected = func(id widget.TreeNodeID) {
+		g.schemaTree.Unselect(id)
+		// Selecting a field inserts it into the query
+		if parts := strings.Split(id, "/"); len(parts) > 1 {
+			g.insertAtCursor("", parts[len(parts)-1])
+		}
+	}
+
+	body := container.NewHSplit(editors, g.schemaTree)
+	body.SetOffset(0.65)
+
+	return container.NewBorder(toolbar, nil, nil, nil, body)
+}
+
+// Load fills the editor from a GraphQL body
+func (g *graphQLEditor) Load(body *models.GraphQLBody) {
+	if body == nil {
+		body = &models.GraphQLBody{}
+	}
+	g.queryEntry.SetText(body.Query)
+	g.variablesEntry.SetText(body.Variables)
+	g.refreshOperations()
+	if body.OperationName != "" {
+		g.operationSelect.SetSelected(body.OperationName)
+	} else {
+		g.operationSelect.ClearSelected()
+	}
+}
+
+// Read returns the GraphQL body entered in the editor
+func (g *graphQLEditor) Read() *models.GraphQLBody {
+	return &models.GraphQLBody{
+		Query:         g.queryEntry.Text,
+		Variables:     g.variablesEntry.Text,
+		OperationName: g.operationSelect.Selected,
+	}
+}
+
+// refreshOperations lists the operations defined in the query
+func (g *graphQLEditor) refreshOperations() {
+	names := httpclient.GraphQLOperationNames(g.queryEntry.Text)
+	selected := g.operationSelect.Selected
+	g.operationSelect.SetOptions(names)
+
+	for _, name := range names {
+		if name == selected {
+			return
+		}
+	}
+	g.operationSelect.ClearSelected()
+}
+
+// fetchSchema runs an introspection query against the request's URL
+func (g *graphQLEditor) fetchSchema() {
+	req := models.NewRequest()
+	g.app.request.UpdateRequest(req)
+	req = g.app.resolveRequest(req)
+
+	g.schemaStatus.SetText("Fetching schema...")
+	go func() {
+		schema, err := g.app.httpClient.IntrospectGraphQL(req)
+		fyne.Do(func() {
+			if err != nil {
+				g.schemaStatus.SetText("Schema error: " + err.Error())
+				g.schemaStatus.Importance = widget.DangerImportance
+				g.schemaStatus.Refresh()
+				return
+			}
+			g.schema = schema
+			g.schemaStatus.SetText(fmt.Sprintf("%d types", len(schema.TypeNames)))
+			g.schemaStatus.Importance = widget.LowImportance
+			g.schemaStatus.Refresh()
+			g.schemaTree.Refresh()
+			g.refreshSuggestions()
+		})
+	}()
+}
+
+// cursorOffset converts the entry's cursor row and column to a byte offset
+func (g *graphQLEditor) cursorOffset() int {
+	text := g.queryEntry.Text
+	offset := 0
+	for row := 0; row < g.queryEntry.CursorRow; row++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	for col := 0; col < g.queryEntry.CursorColumn && offset < len(text) && text[offset] != '\n'; col++ {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// refreshSuggestions lists the fields that can be typed at the cursor
+func (g *graphQLEditor) refreshSuggestions() {
+	g.suggestions = nil
+	if g.schema != nil {
+		g.suggestPrefix, g.suggestions = g.schema.Suggest(g.queryEntry.Text, g.cursorOffset())
+		// Only offer completions once something has been typed
+		if g.suggestPrefix == "" {
+			g.suggestions = nil
+		}
+	}
+	if len(g.suggestions) > maxSuggestions {
+		g.suggestions = g.suggestions[:maxSuggestions]
+	}
+
+	if len(g.suggestions) == 0 {
+		g.suggestBox.Hide()
+	} else {
+		g.suggestBox.Show()
+	}
+	g.suggestList.Refresh()
+}
+
+// insertAtCursor replaces prefix before the cursor with text
+func (g *graphQLEditor) insertAtCursor(prefix, text string) {
+	offset := g.cursorOffset()
+	current := g.queryEntry.Text
+	start := offset - len(prefix)
+	if start < 0 || current[start:offset] != prefix {
+		start = offset
+	}
+
+	updated := current[:start] + text + current[offset:]
+	g.queryEntry.SetText(updated)
+
+	// Place the cursor after the inserted text
+	before := updated[:start+len(text)]
+	g.queryEntry.CursorRow = strings.Count(before, "\n")
+	g.queryEntry.CursorColumn = utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:])
+	g.queryEntry.Refresh()
+	g.app.GetWindow().Canvas().Focus(g.queryEntry)
+}
+
+// Schema tree nodes are "Type" for types and "Type/field/field..." for fields
+
+// treeType resolves the type a tree node expands into
+func (g *graphQLEditor) treeType(id widget.TreeNodeID) *httpclient.GraphQLType {
+	if g.schema == nil {
+		return nil
+	}
+	parts := strings.Split(id, "/")
+	typ := g.schema.Types[parts[0]]
+	for _, name := range parts[1:] {
+		if typ == nil {
+			return nil
+		}
+		var next *httpclient.GraphQLType
+		for _, f := range typ.Fields {
+			if f.Name == name {
+				next = g.schema.Types[f.TypeName]
+				break
+			}
+		}
+		typ = next
+	}
+	return typ
+}
+
+func (g *graphQLEditor) treeChildren(id widget.TreeNodeID) []widget.TreeNodeID {
+	if g.schema == nil {
+		return nil
+	}
+	if id == "" {
+		// Root operation types first, then everything else
+		var roots, others []string
+		for _, name := range []string{g.schema.QueryType, g.schema.MutationType, g.schema.SubscriptionType} {
+			if name != "" {
+				roots = append(roots, name)
+			}
+		}
+		for _, name := range g.schema.TypeNames {
+			if name != g.schema.QueryType && name != g.schema.MutationType && name != g.schema.SubscriptionType {
+				others = append(others, name)
+			}
+		}
+		return append(roots, others...)
+	}
+
+	typ := g.treeType(id)
+	if typ == nil {
+		return nil
+	}
+	children := make([]string, len(typ.Fields))
+	for i, f := range typ.Fields {
+		children[i] = id + "/" + f.Name
+	}
+	return children
+}
+
+func (g *graphQLEditor) treeIsBranch(id widget.TreeNodeID) bool {
+	if id == "" {
+		return true
+	}
+	typ := g.treeType(id)
+	return typ != nil && len(typ.Fields) > 0
+}
+
+func (g *graphQLEditor) treeLabel(id widget.TreeNodeID) string {
+	parts := strings.Split(id, "/")
+	if len(parts) == 1 {
+		if typ := g.schema.Types[id]; typ != nil {
+			return fmt.Sprintf("%s (%s)", typ.Name, strings.ToLower(typ.Kind))
+		}
+		return id
+	}
+
+	parent := g.treeType(strings.Join(parts[:len(parts)-1], "/"))
+	name := parts[len(parts)-1]
+	if parent != nil {
+		for _, f := range parent.Fields {
+			if f.Name == name {
+				return fieldSignature(f)
+			}
+		}
+	}
+	return name
+}
+
+// fieldSignature renders a field as "name(arg: Type): Type"
+func fieldSignature(f httpclient.GraphQLField) string {
+	text := f.Name
+	if len(f.Args) > 0 {
+		args := make([]string, len(f.Args))
+		for i, a := range f.Args {
+			args[i] = a.Name + ": " + a.Type
+		}
+		text += "(" + strings.Join(args, ", ") + ")"
+	}
+	return text + ": " + f.Type
+}
@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/models"
+)
+
+// grpcEditor selects the gRPC method to call and where its descriptors come from
+type grpcEditor struct {
+	app *App
+
+	methodSelect     *widget.Select
+	protoFilesEntry  *widget.Entry
+	importPathsEntry *widget.Entry
+	plaintextCheck   *widget.Check
+	statusLabel      *widget.Label
+}
+
+// newGRPCEditor creates a new gRPC editor
+func newGRPCEditor(app *App) *grpcEditor {
+	return &grpcEditor{
+		app: app,
+	}
+}
+
+// Build creates the gRPC editor UI
+func (g *grpcEditor) Build() fyne.CanvasObject {
+	// Method selector, filled from reflection or the .proto files
+	g.methodSelect = widget.NewSelect(nil, func(string) { g.app.request.edited() })
+	g.methodSelect.PlaceHolder = "(select a method)"
+
+	refreshBtn := widget.NewButtonWithIcon("Methods", theme.ViewRefreshIcon(), func() {
+		g.refreshMethods()
+	})
+	templateBtn := widget.NewButtonWithIcon("Message Template", theme.DocumentCreateIcon(), func() {
+		g.fillTemplate()
+	})
+
+	g.plaintextCheck = widget.NewCheck("Plaintext", func(bool) { g.app.request.edited() })
+
+	g.statusLabel = widget.NewLabel("")
+	g.statusLabel.Importance = widget.LowImportance
+
+	methodRow := container.NewBorder(
+		nil, nil,
+		widget.NewLabel("Method"),
+		container.NewHBox(refreshBtn, templateBtn, g.plaintextCheck),
+		g.methodSelect,
+	)
+
+	// .proto files, used instead of server reflection when given
+	g.protoFilesEntry = widget.NewEntry()
+	g.protoFilesEntry.OnChanged = func(string) { g.app.request.edited() }
+	g.protoFilesEntry.SetPlaceHolder(".proto files, comma-separated (empty uses server reflection)")
+	addProtoBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		g.chooseProtoFile()
+	})
+
+	g.importPathsEntry = widget.NewEntry()
+	g.importPathsEntry.OnChanged = func(string) { g.app.request.edited() }
+	g.importPathsEntry.SetPlaceHolder("Import paths, comma-separated")
+
+	protoRow := container.NewGridWithColumns(2,
+		container.NewBorder(nil, nil, widget.NewLabel("Protos"), addProtoBtn, g.protoFilesEntry),
+		container.NewBorder(nil, nil, widget.NewLabel("Imports"), nil, g.importPathsEntry),
+	)
+
+	return container.NewVBox(methodRow, protoRow, g.statusLabel)
+}
+
+// Load fills the editor from a request's gRPC options
+func (g *grpcEditor) Load(options *models.GRPCRequest) {
+	if options == nil {
+		options = &models.GRPCRequest{}
+	}
+	g.protoFilesEntry.SetText(strings.Join(options.ProtoFiles, ", "))
+	g.importPathsEntry.SetText(strings.Join(options.ImportPaths, ", "))
+	g.plaintextCheck.SetChecked(options.Plaintext)
+	g.statusLabel.SetText("")
+
+	if options.Service == "" || options.Method == "" {
+		g.methodSelect.SetOptions(nil)
+		g.methodSelect.ClearSelected()
+		return
+	}
+	// Offer the saved method until the list is refreshed
+	name := options.Service + "/" + options.Method
+	g.methodSelect.SetOptions([]string{name})
+	g.methodSelect.SetSelected(name)
+}
+
+// Read returns the gRPC options entered in the editor
+func (g *grpcEditor) Read() *models.GRPCRequest {
+	options := &models.GRPCRequest{
+		ProtoFiles:  splitList(g.protoFilesEntry.Text),
+		ImportPaths: splitList(g.importPathsEntry.Text),
+		Plaintext:   g.plaintextCheck.Checked,
+	}
+	if i := strings.LastIndex(g.methodSelect.Selected, "/"); i > 0 {
+		options.Service = g.methodSelect.Selected[:i]
+		options.Method = g.methodSelect.Selected[i+1:]
+	}
+	return options
+}
+
+// refreshMethods lists the methods offered by the target
+func (g *grpcEditor) refreshMethods() {
+	req := models.NewRequest()
+	g.app.request.UpdateRequest(req)
+	req = g.app.resolveRequest(req)
+
+	g.setStatus("Loading methods...", widget.LowImportance)
+	go func() {
+		ctx, cancel := g.app.callContext(req)
+		defer cancel()
+		methods, err := g.app.grpcClient.ListMethods(ctx, req)
+		fyne.Do(func() {
+			if err != nil {
+				g.setStatus("Error: "+err.Error(), widget.DangerImportance)
+				return
+			}
+			selected := g.methodSelect.Selected
+			names := make([]string, len(methods))
+			for i, m := range methods {
+				names[i] = m.FullName()
+			}
+			g.methodSelect.SetOptions(names)
+			g.methodSelect.ClearSelected()
+			for _, name := range names {
+				if name == selected {
+					g.methodSelect.SetSelected(name)
+				}
+			}
+			g.setStatus(fmt.Sprintf("%d methods", len(methods)), widget.LowImportance)
+		})
+	}()
+}
+
+// fillTemplate replaces the body with a skeleton of the method's input message
+func (g *grpcEditor) fillTemplate() {
+	req := models.NewRequest()
+	g.app.request.UpdateRequest(req)
+	req = g.app.resolveRequest(req)
+
+	go func() {
+		ctx, cancel := g.app.callContext(req)
+		defer cancel()
+		text, err := g.app.grpcClient.MessageTemplate(ctx, req)
+		fyne.Do(func() {
+			if err != nil {
+				g.setStatus("Error: "+err.Error(), widget.DangerImportance)
+				return
+			}
+			g.app.request.bodyEntry.SetText(text)
+			g.setStatus("", widget.LowImportance)
+		})
+	}()
+}
+
+// chooseProtoFile adds a .proto file picked from disk
+func (g *grpcEditor) chooseProtoFile() {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		reader.Close()
+		files := append(splitList(g.protoFilesEntry.Text), reader.URI().Path())
+		g.protoFilesEntry.SetText(strings.Join(files, ", "))
+	}, g.app.GetWindow())
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".proto"}))
+	d.Show()
+}
+
+func (g *grpcEditor) setStatus(text string, importance widget.Importance) {
+	g.statusLabel.SetText(text)
+	g.statusLabel.Importance = importance
+	g.statusLabel.Refresh()
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	fynestorage "fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/har"
+	"percentman/models"
+	"percentman/redact"
+)
+
+// importHAR asks whether a HAR file's entries become history items or templates, then imports them
+func (a *App) importHAR(name string, data []byte) {
+	h, err := har.Parse(data)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("import %s: %w", name, err), a.window)
+		return
+	}
+
+	message := widget.NewLabel(fmt.Sprintf("%s has %d entries. Import them as history items or as templates?", name, len(h.Log.Entries)))
+	message.Wrapping = fyne.TextWrapWord
+
+	d := dialog.NewCustomWithoutButtons("Import HAR", message, a.window)
+	historyBtn := widget.NewButton("History", func() {
+		d.Hide()
+		if err := a.storage.ImportHistory(h.History()); err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		a.sidebar.RefreshHistory()
+	})
+	historyBtn.Importance = widget.HighImportance
+	templatesBtn := widget.NewButton("Templates", func() {
+		d.Hide()
+		if _, _, err := a.storage.ImportTemplates(h.Templates()); err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		a.sidebar.RefreshTemplates()
+	})
+	d.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("Cancel", func() { d.Hide() }),
+		templatesBtn,
+		historyBtn,
+	})
+	d.Resize(fyne.NewSize(420, 0))
+	d.Show()
+}
+
+// ShowExportHARDialog lets the user pick history items and saves them as a HAR file
+func (a *App) ShowExportHARDialog() {
+	history := a.storage.GetHistory()
+	if len(history) == 0 {
+		dialog.ShowInformation("Export HAR", "There is no history to export.", a.window)
+		return
+	}
+
+	var popup *widget.PopUp
+
+	titleLabel := widget.NewLabelWithStyle("Export History as HAR", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
+	// Indexes of the selected history items, all of them initially. The list
+	// only creates checks for the rows on screen, so long histories stay responsive.
+	selected := make(map[int]bool, len(history))
+	selectAllItems := func(checked bool) {
+		clear(selected)
+		if checked {
+			for i := range history {
+				selected[i] = true
+			}
+		}
+	}
+	selectAllItems(true)
+
+	items := widget.NewList(
+		func() int { return len(history) },
+		func() fyne.CanvasObject { return widget.NewCheck("", nil) },
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			h := history[id]
+			check := o.(*widget.Check)
+			check.OnChanged = nil
+			check.Text = fmt.Sprintf("%s  %s %s", h.Timestamp.Format("2006-01-02 15:04:05"), methodLabel(&h.Request), h.Request.URL)
+			check.SetChecked(selected[id])
+			check.OnChanged = func(checked bool) {
+				if checked {
+					selected[id] = true
+				} else {
+					delete(selected, id)
+				}
+			}
+		},
+	)
+
+	selectAll := widget.NewCheck("Select all", nil)
+	selectAll.SetChecked(true)
+	selectAll.OnChanged = func(checked bool) {
+		selectAllItems(checked)
+		items.Refresh()
+	}
+
+	exportBtn := widget.NewButton("Export", func() {
+		var chosen []models.HistoryItem
+		for i, h := range history {
+			if selected[i] {
+				chosen = append(chosen, h)
+			}
+		}
+		if len(chosen) == 0 {
+			return
+		}
+		popup.Hide()
+		a.saveHAR(chosen)
+	})
+	exportBtn.Importance = widget.HighImportance
+
+	cancelBtn := widget.NewButton("Cancel", func() {
+		popup.Hide()
+	})
+
+	buttons := container.NewHBox(
+		selectAll,
+		layout.NewSpacer(),
+		cancelBtn,
+		exportBtn,
+	)
+
+	content := container.NewBorder(
+		container.NewVBox(titleLabel, widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), buttons),
+		nil, nil,
+		items,
+	)
+
+	popup = widget.NewModalPopUp(container.NewPadded(content), a.window.Canvas())
+	popup.Resize(fyne.NewSize(640, 420))
+	popup.Show()
+}
+
+// saveHAR asks for a file name and writes the items to it
+func (a *App) saveHAR(items []models.HistoryItem) {
+	// Items stored before the redaction policy changed may hold values it masks now
+	policy := a.storage.GetSettings().Redaction
+	redacted := make([]models.HistoryItem, len(items))
+	for i, item := range items {
+		redact.Item(&item, policy)
+		redacted[i] = item
+	}
+
+	data, err := har.Export(redacted)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		if _, err := writer.Write(data); err != nil {
+			dialog.ShowError(err, a.window)
+		}
+	}, a.window)
+	d.SetFileName("percentman.har")
+	d.SetFilter(fynestorage.NewExtensionFileFilter([]string{".har"}))
+	d.Show()
+}
@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/models"
+	"percentman/storage"
+)
+
+// historyPageSize is how many history items the sidebar shows at once
+const historyPageSize = 50
+
+// dateFormat is how history date filters are entered
+const dateFormat = "2006-01-02"
+
+// Filter choices that select every item
+const (
+	anyMethod = "Any method"
+	anyStatus = "Any status"
+)
+
+// historyMethods maps the method filter choices to HistoryQuery methods
+var historyMethods = []struct{ label, method string }{
+	{anyMethod, ""},
+	{"GET", "GET"},
+	{"POST", "POST"},
+	{"PUT", "PUT"},
+	{"PATCH", "PATCH"},
+	{"DELETE", "DELETE"},
+	{"HEAD", "HEAD"},
+	{"OPTIONS", "OPTIONS"},
+	{"WebSocket", models.RequestTypeWebSocket},
+	{"gRPC", models.RequestTypeGRPC},
+}
+
+// historyStatuses maps the status filter choices to HistoryQuery fields
+var historyStatuses = []struct {
+	label     string
+	class     int
+	failed    bool
+	errorKind string
+}{
+	{anyStatus, 0, false, ""},
+	{"2xx", 2, false, ""},
+	{"3xx", 3, false, ""},
+	{"4xx", 4, false, ""},
+	{"5xx", 5, false, ""},
+	{"Failed", 0, true, ""},
+	{errorKindLabel(models.ErrorKindDNS), 0, true, models.ErrorKindDNS},
+	{errorKindLabel(models.ErrorKindConnect), 0, true, models.ErrorKindConnect},
+	{errorKindLabel(models.ErrorKindTLS), 0, true, models.ErrorKindTLS},
+	{errorKindLabel(models.ErrorKindTimeout), 0, true, models.ErrorKindTimeout},
+	{errorKindLabel(models.ErrorKindCanceled), 0, true, models.ErrorKindCanceled},
+}
+
+// errorKindLabel describes why a request failed, by its models.ErrorKind constant
+func errorKindLabel(kind string) string {
+	switch kind {
+	case models.ErrorKindDNS:
+		return "DNS error"
+	case models.ErrorKindConnect:
+		return "Connection failed"
+	case models.ErrorKindTLS:
+		return "TLS error"
+	case models.ErrorKindTimeout:
+		return "Timed out"
+	case models.ErrorKindCanceled:
+		return "Canceled"
+	}
+	return "Failed"
+}
+
+// historyFilter holds the search and filter widgets of the history section
+type historyFilter struct {
+	searchEntry  *widget.Entry
+	methodSelect *widget.Select
+	statusSelect *widget.Select
+	hostEntry    *widget.Entry
+	fromEntry    *widget.Entry
+	toEntry      *widget.Entry
+	filters      *fyne.Container
+}
+
+// buildHistoryFilter creates the search entry, with the filters shown below
+// it on demand. onChange is called whenever the selection changes.
+func buildHistoryFilter(onChange func()) (*historyFilter, fyne.CanvasObject) {
+	f := &historyFilter{
+		searchEntry: widget.NewEntry(),
+		hostEntry:   widget.NewEntry(),
+		fromEntry:   newDateEntry("From (YYYY-MM-DD)"),
+		toEntry:     newDateEntry("To (YYYY-MM-DD)"),
+	}
+	f.searchEntry.SetPlaceHolder("Search URL, headers, bodies")
+	f.hostEntry.SetPlaceHolder("Host")
+
+	methods := make([]string, len(historyMethods))
+	for i, m := range historyMethods {
+		methods[i] = m.label
+	}
+	f.methodSelect = widget.NewSelect(methods, nil)
+	f.methodSelect.SetSelected(anyMethod)
+	f.methodSelect.OnChanged = func(string) { onChange() }
+	statuses := make([]string, len(historyStatuses))
+	for i, st := range historyStatuses {
+		statuses[i] = st.label
+	}
+	f.statusSelect = widget.NewSelect(statuses, nil)
+	f.statusSelect.SetSelected(anyStatus)
+	f.statusSelect.OnChanged = func(string) { onChange() }
+
+	for _, e := range []*widget.Entry{f.searchEntry, f.hostEntry, f.fromEntry, f.toEntry} {
+		e.OnChanged = func(string) { onChange() }
+	}
+
+	f.filters = container.NewVBox(
+		container.NewGridWithColumns(2, f.methodSelect, f.statusSelect),
+		f.hostEntry,
+		container.NewGridWithColumns(2, f.fromEntry, f.toEntry),
+	)
+	f.filters.Hide()
+
+	filterBtn := widget.NewButtonWithIcon("", theme.MenuDropDownIcon(), nil)
+	filterBtn.OnTapped = func() {
+		if f.filters.Visible() {
+			f.filters.Hide()
+		} else {
+			f.filters.Show()
+		}
+	}
+	filterBtn.Importance = widget.LowImportance
+
+	return f, container.NewVBox(
+		container.NewBorder(nil, nil, nil, filterBtn, f.searchEntry),
+		f.filters,
+	)
+}
+
+// newDateEntry creates an entry for an optional date
+func newDateEntry(placeHolder string) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder(placeHolder)
+	entry.Validator = func(s string) error {
+		_, err := parseDate(s)
+		return err
+	}
+	return entry
+}
+
+// parseDate parses a date in local time, treating empty input as no date
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation(dateFormat, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date such as %s", time.Now().Format(dateFormat))
+	}
+	return t, nil
+}
+
+// Query returns the query selected by the widgets. Dates that do not parse
+// are left out; their entries show the error.
+func (f *historyFilter) Query() storage.HistoryQuery {
+	q := storage.HistoryQuery{
+		Text: strings.TrimSpace(f.searchEntry.Text),
+		Host: strings.TrimSpace(f.hostEntry.Text),
+	}
+	for _, m := range historyMethods {
+		if m.label == f.methodSelect.Selected {
+			q.Method = m.method
+		}
+	}
+	for _, st := range historyStatuses {
+		if st.label == f.statusSelect.Selected {
+			q.StatusClass, q.Failed, q.ErrorKind = st.class, st.failed, st.errorKind
+		}
+	}
+	if from, err := parseDate(f.fromEntry.Text); err == nil {
+		q.From = from
+	}
+	// The To date is included
+	if to, err := parseDate(f.toEntry.Text); err == nil && !to.IsZero() {
+		q.To = to.AddDate(0, 0, 1)
+	}
+	return q
+}
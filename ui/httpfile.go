@@ -0,0 +1,135 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	fynestorage "fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/httpfile"
+	"percentman/models"
+	"percentman/redact"
+)
+
+// allTemplates is the export choice covering every template
+const allTemplates = "All templates"
+
+// importHTTPFile imports the requests of a .http file as templates in a folder
+// named after the file. The file's variables are added to the app variables,
+// replacing existing values of the same name.
+func (a *App) importHTTPFile(name string, data []byte) {
+	f, err := httpfile.Parse(data)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("import %s: %w", name, err), a.window)
+		return
+	}
+
+	// Re-importing the same file updates its templates in place
+	folder := strings.TrimSuffix(name, path.Ext(name))
+	for i := range f.Templates {
+		f.Templates[i].Folder = folder
+		f.Templates[i].Source = "http:" + name + ":" + f.Templates[i].Name
+	}
+	created, updated, err := a.storage.ImportTemplates(f.Templates)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.sidebar.RefreshTemplates()
+
+	if len(f.Variables) > 0 {
+		settings := a.storage.GetSettings()
+		// The map is shared with storage, so the changes go into a copy
+		vars := make(map[string]string, len(settings.Variables)+len(f.Variables))
+		for k, v := range settings.Variables {
+			vars[k] = v
+		}
+		for k, v := range f.Variables {
+			vars[k] = v
+		}
+		settings.Variables = vars
+		if err := a.SaveSettings(settings); err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+	}
+
+	dialog.ShowInformation("Import complete",
+		fmt.Sprintf("%s: %d requests imported (%d new, %d updated), %d variables", name, len(f.Templates), created, updated, len(f.Variables)),
+		a.window)
+}
+
+// ShowExportHTTPDialog asks which templates to export and saves them as a .http file
+func (a *App) ShowExportHTTPDialog() {
+	templates := a.storage.GetTemplates()
+	if len(templates) == 0 {
+		dialog.ShowInformation("Export .http", "There are no templates to export.", a.window)
+		return
+	}
+
+	var folders []string
+	seen := make(map[string]bool)
+	for _, t := range templates {
+		if t.Folder != "" && !seen[t.Folder] {
+			seen[t.Folder] = true
+			folders = append(folders, t.Folder)
+		}
+	}
+	sort.Strings(folders)
+
+	collection := widget.NewSelect(append([]string{allTemplates}, folders...), nil)
+	collection.SetSelected(allTemplates)
+
+	dialog.ShowCustomConfirm("Export .http", "Export", "Cancel", collection, func(ok bool) {
+		if !ok {
+			return
+		}
+		selected := templates
+		fileName := "percentman.http"
+		if collection.Selected != allTemplates {
+			selected = nil
+			for _, t := range templates {
+				if t.Folder == collection.Selected {
+					selected = append(selected, t)
+				}
+			}
+			fileName = collection.Selected + ".http"
+		}
+		a.saveHTTPFile(selected, fileName)
+	}, a.window)
+}
+
+// saveHTTPFile asks for a file name and writes the templates to it
+func (a *App) saveHTTPFile(templates []models.Template, fileName string) {
+	settings := a.storage.GetSettings()
+	redacted := make([]models.Template, len(templates))
+	for i, t := range templates {
+		redact.Request(&t.Request, settings.Redaction)
+		redacted[i] = t
+	}
+	data, skipped := httpfile.Format(redacted, redact.Variables(settings.Variables, settings.Redaction))
+
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		if _, err := writer.Write(data); err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		if skipped > 0 {
+			dialog.ShowInformation("Export .http",
+				fmt.Sprintf("%d WebSocket or gRPC templates have no .http form and were skipped.", skipped),
+				a.window)
+		}
+	}, a.window)
+	d.SetFileName(fileName)
+	d.SetFilter(fynestorage.NewExtensionFileFilter([]string{".http", ".rest"}))
+	d.Show()
+}
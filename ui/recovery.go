@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2/dialog"
+
+	"percentman/storage"
+)
+
+// showLoadErrors tells the user about data files that could not be read and
+// offers to restore each from its newest readable backup
+func (a *App) showLoadErrors() {
+	for _, le := range a.storage.LoadErrors() {
+		a.showLoadError(le)
+	}
+}
+
+// showLoadError explains one unreadable file and asks how to recover it
+func (a *App) showLoadError(le storage.LoadError) {
+	name := filepath.Base(le.File)
+	message := fmt.Sprintf("%s could not be read: %v", name, le.Err)
+	if le.Kept != "" {
+		message += fmt.Sprintf("\n\nThe damaged file was kept as %s.", filepath.Base(le.Kept))
+	}
+
+	if le.Backup == "" {
+		a.storage.DismissLoadError(le.File)
+		dialog.ShowInformation("Could not load "+name, message+"\n\nNo readable backup was found, so percentman starts without it.", a.window)
+		return
+	}
+
+	message += fmt.Sprintf("\n\nRestore the backup %s?", filepath.Base(le.Backup))
+	d := dialog.NewConfirm("Could not load "+name, message, func(restore bool) {
+		if !restore {
+			a.storage.DismissLoadError(le.File)
+			return
+		}
+		if err := a.storage.RestoreBackup(le.File); err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		a.applyStoredSettings()
+		a.request.RefreshDefaults()
+		a.sidebar.RefreshTemplates()
+		a.sidebar.RefreshHistory()
+	}, a.window)
+	d.SetConfirmText("Restore Backup")
+	d.SetDismissText("Start Empty")
+	d.Show()
+}
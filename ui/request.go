@@ -1,6 +1,9 @@
 package ui
 
 import (
+	"slices"
+	"strings"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
@@ -18,8 +21,47 @@ type RequestPanel struct {
 	headersContainer *fyne.Container
 	bodyEntry        *widget.Entry
 	headers          []headerRow
+
+	useDefaultsCheck *widget.Check
+	settingsForm     *settingsForm
+
+	streamCheck *widget.Check
+	sendBtn     *widget.Button
+
+	typeSelect        *widget.Select
+	subprotocolsEntry *widget.Entry
+	subprotocolsRow   fyne.CanvasObject
+	tabs              *container.AppTabs
+	headersTab        *container.TabItem
+	bodyTab           *container.TabItem
+	settingsTab       *container.TabItem
+
+	bodyModeSelect *widget.Select
+	rawBody        fyne.CanvasObject
+	graphQL        *graphQLEditor
+	graphQLBody    fyne.CanvasObject
+	bodyModeRow    fyne.CanvasObject
+
+	grpc    *grpcEditor
+	grpcRow fyne.CanvasObject
+
+	// loading is set while a request is loaded, which is not an edit
+	loading bool
 }
 
+// Request type labels shown in the type selector
+const (
+	typeLabelHTTP      = "HTTP"
+	typeLabelWebSocket = "WebSocket"
+	typeLabelGRPC      = "gRPC"
+)
+
+// Body mode labels shown in the body mode selector
+const (
+	bodyLabelRaw     = "Raw"
+	bodyLabelGraphQL = "GraphQL"
+)
+
 type headerRow struct {
 	keyEntry   *widget.Entry
 	valueEntry *widget.Entry
@@ -31,6 +73,8 @@ func NewRequestPanel(app *App) *RequestPanel {
 	return &RequestPanel{
 		app:     app,
 		headers: []headerRow{},
+		graphQL: newGraphQLEditor(app),
+		grpc:    newGRPCEditor(app),
 	}
 }
 
@@ -42,19 +86,53 @@ func (r *RequestPanel) Build() fyne.CanvasObject {
 		func(value string) {},
 	)
 	r.methodSelect.SetSelected("GET")
+	r.methodSelect.OnChanged = func(string) { r.edited() }
 
 	// URL entry
 	r.urlEntry = widget.NewEntry()
 	r.urlEntry.SetPlaceHolder("Enter URL (e.g., https://api.example.com/users)")
-
-	// Send button
-	sendBtn := widget.NewButtonWithIcon("Send", theme.MediaPlayIcon(), func() {
-		r.app.SendRequest()
+	r.urlEntry.OnChanged = func(string) { r.edited() }
+
+	// Request type selector (callback is set once the panel is built)
+	r.typeSelect = widget.NewSelect([]string{typeLabelHTTP, typeLabelWebSocket, typeLabelGRPC}, nil)
+	r.typeSelect.SetSelected(typeLabelHTTP)
+
+	// Send button (becomes Stop or Disconnect while a stream, call or socket is open)
+	r.sendBtn = widget.NewButtonWithIcon("Send", theme.MediaPlayIcon(), func() {
+		switch {
+		case r.app.IsStreaming():
+			r.app.StopStream()
+		case r.app.IsCalling():
+			r.app.CancelCall()
+		case r.app.IsWebSocketActive():
+			r.app.DisconnectWebSocket()
+		default:
+			r.app.SendRequest()
+		}
 	})
-	sendBtn.Importance = widget.HighImportance
+	r.sendBtn.Importance = widget.HighImportance
+
+	// Stream toggle for Server-Sent Events endpoints
+	r.streamCheck = widget.NewCheck("Stream (SSE)", func(bool) { r.edited() })
+
+	// Top bar: Type + Method + URL + Stream + Send
+	urlContainer := container.NewBorder(
+		nil, nil,
+		container.NewHBox(r.typeSelect, r.methodSelect),
+		container.NewHBox(r.streamCheck, r.sendBtn),
+		r.urlEntry,
+	)
 
-	// Top bar: Method + URL + Send
-	urlContainer := container.NewBorder(nil, nil, r.methodSelect, sendBtn, r.urlEntry)
+	// WebSocket subprotocols (only shown for WebSocket requests)
+	r.subprotocolsEntry = widget.NewEntry()
+	r.subprotocolsEntry.SetPlaceHolder("Subprotocols, comma-separated (e.g., graphql-ws, chat)")
+	r.subprotocolsEntry.OnChanged = func(string) { r.edited() }
+	r.subprotocolsRow = container.NewBorder(nil, nil, widget.NewLabel("Subprotocols"), nil, r.subprotocolsEntry)
+	r.subprotocolsRow.Hide()
+
+	// gRPC method and descriptor source (only shown for gRPC requests)
+	r.grpcRow = r.grpc.Build()
+	r.grpcRow.Hide()
 
 	// Headers section
 	headersLabel := widget.NewLabelWithStyle("Headers", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
@@ -80,20 +158,66 @@ func (r *RequestPanel) Build() fyne.CanvasObject {
 	r.bodyEntry = widget.NewMultiLineEntry()
 	r.bodyEntry.SetPlaceHolder("Request body (JSON)")
 	r.bodyEntry.SetMinRowsVisible(5)
+	r.bodyEntry.OnChanged = func(string) { r.edited() }
+
+	// Raw text body, or separate query and variables editors for GraphQL
+	r.rawBody = r.bodyEntry
+	r.graphQLBody = r.graphQL.Build()
+	r.graphQLBody.Hide()
+	r.bodyModeSelect = widget.NewSelect([]string{bodyLabelRaw, bodyLabelGraphQL}, func(value string) {
+		if value == bodyLabelGraphQL {
+			r.rawBody.Hide()
+			r.graphQLBody.Show()
+		} else {
+			r.graphQLBody.Hide()
+			r.rawBody.Show()
+		}
+		r.edited()
+	})
+	r.bodyModeSelect.SetSelected(bodyLabelRaw)
 
-	bodySection := container.NewBorder(bodyLabel, nil, nil, nil, r.bodyEntry)
-
-	// Tabs for Headers and Body
-	tabs := container.NewAppTabs(
-		container.NewTabItem("Headers", headersSection),
-		container.NewTabItem("Body", bodySection),
+	// gRPC messages are always JSON, so the mode selector is hidden for them
+	r.bodyModeRow = r.bodyModeSelect
+	bodySection := container.NewBorder(
+		container.NewHBox(bodyLabel, r.bodyModeRow),
+		nil, nil, nil,
+		container.NewStack(r.rawBody, r.graphQLBody),
 	)
 
+	// Settings section (per-request transport options)
+	r.settingsForm = newSettingsForm()
+	r.settingsForm.SetOnChanged(r.edited)
+	r.useDefaultsCheck = widget.NewCheck("Use app defaults", func(checked bool) {
+		if checked {
+			r.settingsForm.Load(r.app.httpClient.Defaults())
+		}
+		r.settingsForm.SetEnabled(!checked)
+		r.edited()
+	})
+	r.useDefaultsCheck.SetChecked(true)
+
+	settingsSection := container.NewVScroll(container.NewVBox(
+		r.useDefaultsCheck,
+		r.settingsForm.Build(),
+	))
+
+	// Tabs for Headers, Body and Settings
+	r.headersTab = container.NewTabItem("Headers", headersSection)
+	r.bodyTab = container.NewTabItem("Body", bodySection)
+	r.settingsTab = container.NewTabItem("Settings", settingsSection)
+	r.tabs = container.NewAppTabs(r.headersTab, r.bodyTab, r.settingsTab)
+
+	r.typeSelect.OnChanged = func(string) {
+		r.applyType()
+		r.app.onRequestTypeChanged(r.isWebSocket())
+		r.edited()
+	}
+
 	// Main layout
 	return container.NewBorder(
-		urlContainer,
+		container.NewVBox(urlContainer, r.subprotocolsRow, r.grpcRow),
 		nil, nil, nil,
-		tabs,
+		r.tabs,
 	)
 }
 
@@ -110,6 +234,10 @@ func (r *RequestPanel) addHeaderRow(key, value string, enabled bool) {
 	enabledCheck := widget.NewCheck("", nil)
 	enabledCheck.SetChecked(enabled)
 
+	keyEntry.OnChanged = func(string) { r.edited() }
+	valueEntry.OnChanged = func(string) { r.edited() }
+	enabledCheck.OnChanged = func(bool) { r.edited() }
+
 	row := headerRow{
 		keyEntry:   keyEntry,
 		valueEntry: valueEntry,
@@ -131,6 +259,7 @@ func (r *RequestPanel) addHeaderRow(key, value string, enabled bool) {
 
 	r.headersContainer.Add(rowContainer)
 	r.headersContainer.Refresh()
+	r.edited()
 }
 
 // removeHeaderRow removes a header row
@@ -160,13 +289,34 @@ func (r *RequestPanel) removeHeaderRow(index int) {
 		r.headersContainer.Add(rowContainer)
 	}
 	r.headersContainer.Refresh()
+	r.edited()
 }
 
 // UpdateRequest updates the request model from UI state
 func (r *RequestPanel) UpdateRequest(req *models.Request) {
+	req.Type = ""
+	req.Subprotocols = nil
+	req.GRPC = nil
+	switch {
+	case r.isWebSocket():
+		req.Type = models.RequestTypeWebSocket
+		req.Subprotocols = splitList(r.subprotocolsEntry.Text)
+	case r.isGRPC():
+		req.Type = models.RequestTypeGRPC
+		req.GRPC = r.grpc.Read()
+	}
+
 	req.Method = r.methodSelect.Selected
 	req.URL = r.urlEntry.Text
 	req.Body = r.bodyEntry.Text
+	req.Stream = r.streamCheck.Checked
+
+	req.BodyMode = ""
+	req.GraphQL = nil
+	if r.bodyModeSelect.Selected == bodyLabelGraphQL {
+		req.BodyMode = models.BodyModeGraphQL
+		req.GraphQL = r.graphQL.Read()
+	}
 
 	req.Headers = []models.Header{}
 	for _, h := range r.headers {
@@ -178,13 +328,57 @@ func (r *RequestPanel) UpdateRequest(req *models.Request) {
 			})
 		}
 	}
+
+	// Keep the previous settings if the form holds an invalid duration
+	if r.useDefaultsCheck.Checked {
+		req.Settings = nil
+	} else if settings, err := r.settingsForm.Read(); err == nil {
+		req.Settings = &settings
+	}
 }
 
 // LoadRequest loads a request into the UI
 func (r *RequestPanel) LoadRequest(req *models.Request) {
-	r.methodSelect.SetSelected(req.Method)
+	r.loading = true
+	defer func() { r.loading = false }()
+
+	switch {
+	case req.IsWebSocket():
+		r.typeSelect.SetSelected(typeLabelWebSocket)
+	case req.IsGRPC():
+		r.typeSelect.SetSelected(typeLabelGRPC)
+	default:
+		r.typeSelect.SetSelected(typeLabelHTTP)
+	}
+	r.subprotocolsEntry.SetText(strings.Join(req.Subprotocols, ", "))
+	r.grpc.Load(req.GRPC)
+
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	// Imported requests may use methods that are not offered by default
+	if !slices.Contains(r.methodSelect.Options, method) {
+		r.methodSelect.SetOptions(append(r.methodSelect.Options, method))
+	}
+	r.methodSelect.SetSelected(method)
 	r.urlEntry.SetText(req.URL)
 	r.bodyEntry.SetText(req.Body)
+	r.streamCheck.SetChecked(req.Stream)
+
+	if req.BodyMode == models.BodyModeGraphQL {
+		r.bodyModeSelect.SetSelected(bodyLabelGraphQL)
+	} else {
+		r.bodyModeSelect.SetSelected(bodyLabelRaw)
+	}
+	r.graphQL.Load(req.GraphQL)
+
+	if req.Settings != nil {
+		r.useDefaultsCheck.SetChecked(false)
+		r.settingsForm.Load(*req.Settings)
+	} else {
+		r.useDefaultsCheck.SetChecked(true)
+	}
 
 	// Clear and rebuild headers
 	r.headers = []headerRow{}
@@ -198,3 +392,80 @@ func (r *RequestPanel) LoadRequest(req *models.Request) {
 		}
 	}
 }
+
+// edited tells the app that the user changed the request
+func (r *RequestPanel) edited() {
+	if !r.loading {
+		r.app.onRequestEdited()
+	}
+}
+
+// RefreshDefaults shows the current app defaults when the request uses them
+func (r *RequestPanel) RefreshDefaults() {
+	if r.useDefaultsCheck.Checked {
+		r.settingsForm.Load(r.app.httpClient.Defaults())
+	}
+}
+
+// SetActive switches the Send button while a stream or WebSocket is open
+func (r *RequestPanel) SetActive(active bool) {
+	switch {
+	case active && r.isWebSocket():
+		r.sendBtn.SetText("Disconnect")
+		r.sendBtn.SetIcon(theme.MediaStopIcon())
+		r.sendBtn.Importance = widget.DangerImportance
+	case active:
+		r.sendBtn.SetText("Stop")
+		r.sendBtn.SetIcon(theme.MediaStopIcon())
+		r.sendBtn.Importance = widget.DangerImportance
+	case r.isWebSocket():
+		r.sendBtn.SetText("Connect")
+		r.sendBtn.SetIcon(theme.LoginIcon())
+		r.sendBtn.Importance = widget.HighImportance
+	default:
+		r.sendBtn.SetText("Send")
+		r.sendBtn.SetIcon(theme.MediaPlayIcon())
+		r.sendBtn.Importance = widget.HighImportance
+	}
+	r.sendBtn.Refresh()
+}
+
+// isGRPC reports whether the gRPC request type is selected
+func (r *RequestPanel) isGRPC() bool {
+	return r.typeSelect.Selected == typeLabelGRPC
+}
+
+// isWebSocket reports whether the WebSocket request type is selected
+func (r *RequestPanel) isWebSocket() bool {
+	return r.typeSelect.Selected == typeLabelWebSocket
+}
+
+// applyType shows the controls that apply to the selected request type
+func (r *RequestPanel) applyType() {
+	switch {
+	case r.isWebSocket():
+		// Messages are composed in the WebSocket panel, so there is no body tab
+		r.methodSelect.Hide()
+		r.streamCheck.Hide()
+		r.subprotocolsRow.Show()
+		r.grpcRow.Hide()
+		r.tabs.SetItems([]*container.TabItem{r.headersTab, r.settingsTab})
+	case r.isGRPC():
+		// The body is the JSON request message; headers are sent as metadata
+		r.methodSelect.Hide()
+		r.streamCheck.Hide()
+		r.subprotocolsRow.Hide()
+		r.grpcRow.Show()
+		r.bodyModeSelect.SetSelected(bodyLabelRaw)
+		r.bodyModeRow.Hide()
+		r.tabs.SetItems([]*container.TabItem{r.headersTab, r.bodyTab, r.settingsTab})
+	default:
+		r.methodSelect.Show()
+		r.streamCheck.Show()
+		r.subprotocolsRow.Hide()
+		r.grpcRow.Hide()
+		r.bodyModeRow.Show()
+		r.tabs.SetItems([]*container.TabItem{r.headersTab, r.bodyTab, r.settingsTab})
+	}
+	r.SetActive(false)
+}
@@ -1,7 +1,9 @@
 package ui
 
 import (
+	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -10,6 +12,7 @@ import (
 
 	httpclient "percentman/http"
 	"percentman/models"
+	"percentman/openapi"
 )
 
 // ResponsePanel represents the response display panel
@@ -18,12 +21,38 @@ type ResponsePanel struct {
 
 	statusLabel *widget.Label
 	timeLabel   *widget.Label
+	sizeLabel   *widget.Label
 	headersText *widget.Entry
 	bodyText    *widget.Entry
+	rawText     *widget.Entry
 	lastHeaders string
 	lastBody    string
+	lastRaw     string
+
+	tabs       *container.AppTabs
+	eventsTab  *container.TabItem
+	eventsList *widget.List
+	events     []models.ServerEvent
+	streaming  bool
+
+	// calling is set while a gRPC call is running
+	calling      bool
+	callMessages []string
+
+	graphQLLabel *widget.Label
+	errorsTab    *container.TabItem
+	errorsList   *widget.List
+	errors       []string
+
+	validationLabel *widget.Label
+	validationTab   *container.TabItem
+	validationList  *widget.List
+	validation      *openapi.Validation
 }
 
+// maxRawDumpBytes limits how much of the body is shown in the raw view
+const maxRawDumpBytes = 64 * 1024
+
 // NewResponsePanel creates a new response panel
 func NewResponsePanel(app *App) *ResponsePanel {
 	return &ResponsePanel{
@@ -36,6 +65,12 @@ func (r *ResponsePanel) Build() fyne.CanvasObject {
 	// Status and time labels
 	r.statusLabel = widget.NewLabel("Status: -")
 	r.timeLabel = widget.NewLabel("Time: -")
+	r.sizeLabel = widget.NewLabel("Size: -")
+	r.graphQLLabel = widget.NewLabel("")
+	r.graphQLLabel.Importance = widget.DangerImportance
+	r.graphQLLabel.Hide()
+	r.validationLabel = widget.NewLabel("")
+	r.validationLabel.Hide()
 
 	statusBar := container.NewHBox(
 		widget.NewIcon(theme.InfoIcon()),
@@ -44,6 +79,10 @@ func (r *ResponsePanel) Build() fyne.CanvasObject {
 		r.statusLabel,
 		widget.NewSeparator(),
 		r.timeLabel,
+		widget.NewSeparator(),
+		r.sizeLabel,
+		r.graphQLLabel,
+		r.validationLabel,
 	)
 
 	// Response headers - enabled for better readability
@@ -80,35 +119,137 @@ func (r *ResponsePanel) Build() fyne.CanvasObject {
 		r.bodyText,
 	)
 
-	// Tabs for Headers and Body
-	tabs := container.NewAppTabs(
+	// Raw body bytes as received, before decompression
+	r.rawText = widget.NewMultiLineEntry()
+	r.rawText.SetPlaceHolder("Raw response bytes will appear here")
+	r.rawText.TextStyle = fyne.TextStyle{Monospace: true}
+	// Make it read-only by reverting changes
+	r.rawText.OnChanged = func(s string) {
+		if s != r.lastRaw {
+			r.rawText.SetText(r.lastRaw)
+		}
+	}
+
+	rawSection := container.NewBorder(
+		widget.NewLabel("Raw bytes"),
+		nil, nil, nil,
+		r.rawText,
+	)
+
+	// Server-Sent Events received while streaming
+	r.eventsList = widget.NewList(
+		func() int {
+			return len(r.events)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(eventText(r.events[id]))
+		},
+	)
+	r.eventsList.OnSelected = func(id widget.ListItemID) {
+		// Show the full event in the body view
+		r.lastBody = httpclient.FormatEvent(r.events[id])
+		r.bodyText.SetText(r.lastBody)
+		r.eventsList.Unselect(id)
+		r.tabs.SelectIndex(0)
+	}
+	r.eventsTab = container.NewTabItem("Events", r.eventsList)
+
+	// GraphQL errors, shown only when the response carries any
+	r.errorsList = widget.NewList(
+		func() int {
+			return len(r.errors)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Importance = widget.DangerImportance
+			label.Wrapping = fyne.TextWrapWord
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(r.errors[id])
+		},
+	)
+	r.errorsTab = container.NewTabItem("Errors", r.errorsList)
+
+	// OpenAPI schema violations, shown only when a document is loaded
+	r.validationList = widget.NewList(
+		func() int {
+			if r.validation == nil {
+				return 0
+			}
+			return max(len(r.validation.Violations), 1)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Wrapping = fyne.TextWrapWord
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			if r.validation.Valid() {
+				label.SetText("Response matches " + r.validation.Operation)
+				label.Importance = widget.SuccessImportance
+			} else {
+				v := r.validation.Violations[id]
+				pointer := v.Pointer
+				if pointer == "" {
+					pointer = "(response)"
+				}
+				label.SetText(pointer + ": " + v.Message)
+				label.Importance = widget.DangerImportance
+			}
+			label.Refresh()
+		},
+	)
+	r.validationTab = container.NewTabItem("Validation", r.validationList)
+
+	// Tabs for Body, Headers, Raw and Events
+	r.tabs = container.NewAppTabs(
 		container.NewTabItem("Body", bodySection),
 		container.NewTabItem("Headers", headersSection),
+		container.NewTabItem("Raw", rawSection),
+		r.eventsTab,
 	)
 
 	return container.NewBorder(
 		statusBar,
 		nil, nil, nil,
-		tabs,
+		r.tabs,
 	)
 }
 
 // DisplayResponse displays the HTTP response
 func (r *ResponsePanel) DisplayResponse(resp *models.Response) {
+	r.ShowValidation(nil)
 	if resp.Error != "" {
 		r.statusLabel.SetText("Error: " + resp.Error)
 		r.statusLabel.Importance = widget.DangerImportance
 		r.timeLabel.SetText("Time: -")
+		r.sizeLabel.SetText("Size: -")
 		r.lastBody = ""
 		r.lastHeaders = ""
+		r.lastRaw = ""
 		r.bodyText.SetText("")
 		r.headersText.SetText("")
+		r.rawText.SetText("")
+		r.showGraphQLErrors(nil)
 		return
 	}
 
-	// Status
+	// Status (gRPC reports status codes where 0 means OK)
 	r.statusLabel.SetText(fmt.Sprintf("Status: %s", resp.Status))
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+	if r.app.tab.request.IsGRPC() {
+		if resp.StatusCode == 0 {
+			r.statusLabel.Importance = widget.SuccessImportance
+		} else {
+			r.statusLabel.Importance = widget.DangerImportance
+		}
+	} else if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		r.statusLabel.Importance = widget.SuccessImportance
 	} else if resp.StatusCode >= 400 {
 		r.statusLabel.Importance = widget.DangerImportance
@@ -119,10 +260,26 @@ func (r *ResponsePanel) DisplayResponse(resp *models.Response) {
 	// Time
 	r.timeLabel.SetText(fmt.Sprintf("Time: %dms", resp.ResponseTime.Milliseconds()))
 
-	// Headers
+	// Size (compressed on the wire, and decoded if different)
+	r.sizeLabel.SetText(sizeText(resp))
+	if resp.DecodeError != "" || resp.Truncated {
+		r.sizeLabel.Importance = widget.WarningImportance
+	} else {
+		r.sizeLabel.Importance = widget.MediumImportance
+	}
+
+	// Headers, followed by gRPC trailers
 	headersStr := ""
 	for k, v := range resp.Headers {
-		headersStr += fmt.Sprintf("%s: %s\n", k, v)
+		for _, value := range models.SplitHeader(k, v) {
+			headersStr += fmt.Sprintf("%s: %s\n", k, value)
+		}
+	}
+	if len(resp.Trailers) > 0 {
+		headersStr += "\nTrailers:\n"
+		for k, v := range resp.Trailers {
+			headersStr += fmt.Sprintf("%s: %s\n", k, v)
+		}
 	}
 	r.lastHeaders = headersStr
 	r.headersText.SetText(headersStr)
@@ -134,6 +291,214 @@ func (r *ResponsePanel) DisplayResponse(resp *models.Response) {
 	}
 	r.lastBody = body
 	r.bodyText.SetText(body)
+
+	// GraphQL reports failures in the body, often with HTTP 200
+	if r.app.tab.request.IsGraphQL() {
+		r.showGraphQLErrors(httpclient.GraphQLErrors(resp.Body))
+	} else {
+		r.showGraphQLErrors(nil)
+	}
+
+	// Raw bytes as a hex dump
+	raw := resp.RawBody
+	truncated := len(raw) > maxRawDumpBytes
+	if truncated {
+		raw = raw[:maxRawDumpBytes]
+	}
+	dump := hex.Dump(raw)
+	if truncated {
+		dump += fmt.Sprintf("... (%s more)\n", formatSize(int64(len(resp.RawBody)-maxRawDumpBytes)))
+	}
+	r.lastRaw = dump
+	r.rawText.SetText(dump)
+}
+
+// showGraphQLErrors shows the errors array of a GraphQL response, if any
+func (r *ResponsePanel) showGraphQLErrors(errs []string) {
+	r.errors = errs
+	r.errorsList.Refresh()
+
+	if len(errs) == 0 {
+		r.graphQLLabel.Hide()
+		r.tabs.Remove(r.errorsTab)
+		return
+	}
+
+	r.graphQLLabel.SetText(fmt.Sprintf("GraphQL errors: %d", len(errs)))
+	r.graphQLLabel.Show()
+	if !containsTab(r.tabs, r.errorsTab) {
+		r.tabs.Append(r.errorsTab)
+	}
+	r.tabs.Select(r.errorsTab)
+}
+
+// ShowValidation shows the result of checking the response against an OpenAPI document.
+// Responses that match no operation in the document, or a nil result, hide the tab.
+func (r *ResponsePanel) ShowValidation(v *openapi.Validation) {
+	if v == nil || v.Operation == "" {
+		r.validation = nil
+		r.validationLabel.Hide()
+		r.tabs.Remove(r.validationTab)
+		return
+	}
+
+	r.validation = v
+	r.validationList.Refresh()
+	if v.Valid() {
+		r.validationLabel.SetText("Schema: valid")
+		r.validationLabel.Importance = widget.SuccessImportance
+	} else {
+		r.validationLabel.SetText(fmt.Sprintf("Schema: %d violations", len(v.Violations)))
+		r.validationLabel.Importance = widget.DangerImportance
+	}
+	r.validationLabel.Refresh()
+	r.validationLabel.Show()
+	if !containsTab(r.tabs, r.validationTab) {
+		r.tabs.Append(r.validationTab)
+	}
+}
+
+// containsTab reports whether tabs currently shows item
+func containsTab(tabs *container.AppTabs, item *container.TabItem) bool {
+	for _, t := range tabs.Items {
+		if t == item {
+			return true
+		}
+	}
+	return false
+}
+
+// StartStream prepares the panel for a new event stream
+func (r *ResponsePanel) StartStream() {
+	r.Clear()
+	r.streaming = true
+	r.statusLabel.SetText("Status: Connecting...")
+	r.tabs.Select(r.eventsTab)
+}
+
+// ShowStreamConnect displays the status and headers of a stream connection
+func (r *ResponsePanel) ShowStreamConnect(resp *models.Response) {
+	if !r.streaming {
+		return
+	}
+	r.statusLabel.SetText(fmt.Sprintf("Status: %s (streaming)", resp.Status))
+	if resp.StatusCode == 200 {
+		r.statusLabel.Importance = widget.SuccessImportance
+	} else {
+		r.statusLabel.Importance = widget.DangerImportance
+	}
+	r.statusLabel.Refresh()
+	r.timeLabel.SetText(fmt.Sprintf("Time: %dms", resp.ResponseTime.Milliseconds()))
+
+	headersStr := ""
+	for k, v := range resp.Headers {
+		headersStr += fmt.Sprintf("%s: %s\n", k, v)
+	}
+	r.lastHeaders = headersStr
+	r.headersText.SetText(headersStr)
+}
+
+// AppendEvent adds a received event to the events list
+func (r *ResponsePanel) AppendEvent(e models.ServerEvent) {
+	if !r.streaming {
+		return
+	}
+	r.events = append(r.events, e)
+	r.sizeLabel.SetText(fmt.Sprintf("Events: %d", len(r.events)))
+	r.eventsList.Refresh()
+	r.eventsList.ScrollToBottom()
+}
+
+// ShowStreamError displays a stream failure; reconnecting is true when the stream will retry
+func (r *ResponsePanel) ShowStreamError(err error, reconnecting bool) {
+	if !r.streaming {
+		return
+	}
+	if reconnecting {
+		r.statusLabel.SetText("Status: Reconnecting (" + err.Error() + ")")
+		r.statusLabel.Importance = widget.WarningImportance
+	} else {
+		r.statusLabel.SetText("Error: " + err.Error())
+		r.statusLabel.Importance = widget.DangerImportance
+	}
+	r.statusLabel.Refresh()
+}
+
+// EndStream marks the stream as closed
+func (r *ResponsePanel) EndStream() {
+	if !r.streaming {
+		return
+	}
+	r.streaming = false
+	if r.statusLabel.Importance != widget.DangerImportance {
+		r.statusLabel.SetText("Status: Stream closed")
+		r.statusLabel.Importance = widget.MediumImportance
+		r.statusLabel.Refresh()
+	}
+}
+
+// StartCall prepares the panel for a gRPC call
+func (r *ResponsePanel) StartCall() {
+	r.Clear()
+	r.calling = true
+	r.statusLabel.SetText("Status: Calling...")
+}
+
+// AppendCallMessage shows a message of a server-streaming call as it arrives
+func (r *ResponsePanel) AppendCallMessage(text string) {
+	if !r.calling {
+		return
+	}
+	r.callMessages = append(r.callMessages, text)
+	r.sizeLabel.SetText(fmt.Sprintf("Messages: %d", len(r.callMessages)))
+	r.lastBody = strings.Join(r.callMessages, "\n")
+	r.bodyText.SetText(r.lastBody)
+}
+
+// EndCall displays the result of the running gRPC call
+func (r *ResponsePanel) EndCall(resp *models.Response) {
+	if !r.calling {
+		return
+	}
+	r.calling = false
+	r.callMessages = nil
+	r.DisplayResponse(resp)
+}
+
+// eventText formats an event as a single list line
+func eventText(e models.ServerEvent) string {
+	text := e.Timestamp.Format("15:04:05.000") + "  [" + e.Event + "]"
+	if e.ID != "" {
+		text += " id=" + e.ID
+	}
+	return text + "  " + e.Data
+}
+
+// sizeText describes the body size for the status bar
+func sizeText(resp *models.Response) string {
+	text := "Size: " + formatSize(resp.Size)
+	if resp.DecodeError != "" {
+		return text + " (" + resp.DecodeError + ")"
+	}
+	if resp.Truncated {
+		return text + fmt.Sprintf(" (%s, decoded body cut at %s)", resp.ContentEncoding, formatSize(resp.DecodedSize))
+	}
+	if resp.ContentEncoding != "" {
+		text += fmt.Sprintf(" (%s, %s decoded)", resp.ContentEncoding, formatSize(resp.DecodedSize))
+	}
+	return text
+}
+
+// formatSize formats a byte count for display
+func formatSize(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
 }
 
 // Clear clears the response panel
@@ -141,8 +506,19 @@ func (r *ResponsePanel) Clear() {
 	r.statusLabel.SetText("Status: -")
 	r.statusLabel.Importance = widget.MediumImportance
 	r.timeLabel.SetText("Time: -")
+	r.sizeLabel.SetText("Size: -")
+	r.sizeLabel.Importance = widget.MediumImportance
 	r.lastHeaders = ""
 	r.lastBody = ""
+	r.lastRaw = ""
 	r.headersText.SetText("")
 	r.bodyText.SetText("")
+	r.rawText.SetText("")
+	r.events = nil
+	r.streaming = false
+	r.calling = false
+	r.callMessages = nil
+	r.eventsList.Refresh()
+	r.showGraphQLErrors(nil)
+	r.ShowValidation(nil)
 }
@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"gopkg.in/yaml.v3"
+
+	"percentman/diff"
+	httpclient "percentman/http"
+	"percentman/models"
+)
+
+// ShowRevisionsDialog lists the earlier versions of a template, shows how one
+// differs from another and restores the one picked
+func (a *App) ShowRevisionsDialog(id string) {
+	current := a.storage.GetTemplateByID(id)
+	if current == nil {
+		return
+	}
+	revisions, err := a.storage.TemplateRevisions(id)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	if len(revisions) == 0 {
+		dialog.ShowInformation("Template History", fmt.Sprintf("%q has no earlier versions yet.", current.Name), a.window)
+		return
+	}
+
+	// The current version comes first, followed by the earlier ones, newest first
+	versions := append([]models.TemplateRevision{{
+		Revision: current.Revision,
+		Name:     current.Name,
+		Request:  current.Request,
+		SavedAt:  current.UpdatedAt,
+	}}, revisions...)
+	labels := make([]string, len(versions))
+	for i, v := range versions {
+		labels[i] = revisionLabel(v, i == 0)
+	}
+
+	var popup *widget.PopUp
+
+	titleLabel := widget.NewLabelWithStyle(fmt.Sprintf("History of %q", current.Name), fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
+	diffText := widget.NewRichText()
+	diffScroll := container.NewScroll(diffText)
+
+	restoreBtn := widget.NewButton("Restore", nil)
+	restoreBtn.Importance = widget.HighImportance
+
+	revisionSelect := widget.NewSelect(labels, nil)
+	compareSelect := widget.NewSelect(labels, nil)
+	update := func() {
+		from, to := revisionSelect.SelectedIndex(), compareSelect.SelectedIndex()
+		if from < 0 || to < 0 {
+			return
+		}
+		diffText.Segments = diffSegments(diff.Lines(requestText(&versions[from].Request), requestText(&versions[to].Request)))
+		diffText.Refresh()
+		diffScroll.ScrollToTop()
+		if from == 0 {
+			restoreBtn.Disable()
+		} else {
+			restoreBtn.Enable()
+		}
+	}
+	revisionSelect.OnChanged = func(string) { update() }
+	compareSelect.OnChanged = func(string) { update() }
+
+	restoreBtn.OnTapped = func() {
+		popup.Hide()
+		a.RestoreTemplate(id, versions[revisionSelect.SelectedIndex()].Revision)
+	}
+	closeBtn := widget.NewButton("Close", func() {
+		popup.Hide()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Revision", revisionSelect),
+		widget.NewFormItem("Compare with", compareSelect),
+	)
+	buttons := container.NewHBox(
+		layout.NewSpacer(),
+		closeBtn,
+		restoreBtn,
+	)
+
+	content := container.NewBorder(
+		container.NewVBox(titleLabel, widget.NewSeparator(), form),
+		container.NewVBox(widget.NewSeparator(), buttons),
+		nil, nil,
+		diffScroll,
+	)
+
+	compareSelect.SetSelectedIndex(0)
+	revisionSelect.SetSelectedIndex(1)
+
+	popup = widget.NewModalPopUp(container.NewPadded(content), a.window.Canvas())
+	popup.Resize(fyne.NewSize(720, 520))
+	popup.Show()
+}
+
+// RestoreTemplate makes an earlier version of a template its current one. Tabs
+// showing the template follow it unless they have edits of their own.
+func (a *App) RestoreTemplate(id string, revision int) {
+	restored, err := a.storage.RestoreTemplate(id, revision)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.syncRequest()
+	for _, tab := range a.tabs {
+		if tab.template != nil && tab.template.ID == id && !tab.dirty() {
+			latest := *restored
+			a.reloadOpenTemplate(tab, &latest)
+		}
+	}
+	a.sidebar.RefreshTemplates()
+	a.saveSession()
+}
+
+// revisionLabel describes a version of a template in the revision pickers
+func revisionLabel(r models.TemplateRevision, current bool) string {
+	label := fmt.Sprintf("Revision %d, saved %s", r.Revision, r.SavedAt.Local().Format("2006-01-02 15:04:05"))
+	if current {
+		label += " (current)"
+	}
+	return label
+}
+
+// requestText writes req as YAML for comparing versions, with JSON bodies
+// pretty-printed so that changes show up line by line
+func requestText(req *models.Request) string {
+	r := req.Clone()
+	if httpclient.IsJSON(r.Body) {
+		r.Body = httpclient.FormatJSON(r.Body)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(r); err != nil {
+		return err.Error()
+	}
+	enc.Close()
+	return buf.String()
+}
+
+// diffSegments shows a comparison in a monospace font, with removed lines in
+// red and added ones in green
+func diffSegments(lines []diff.Line) []widget.RichTextSegment {
+	if !diff.Changed(lines) {
+		return []widget.RichTextSegment{&widget.TextSegment{
+			Text:  "The versions have the same request.",
+			Style: widget.RichTextStyleInline,
+		}}
+	}
+
+	segments := make([]widget.RichTextSegment, len(lines))
+	for i, l := range lines {
+		style := widget.RichTextStyleCodeBlock
+		prefix := "  "
+		switch l.Op {
+		case diff.Delete:
+			prefix = "- "
+			style.ColorName = theme.ColorNameError
+		case diff.Insert:
+			prefix = "+ "
+			style.ColorName = theme.ColorNameSuccess
+		}
+		segments[i] = &widget.TextSegment{Text: prefix + l.Text, Style: style}
+	}
+	return segments
+}
@@ -0,0 +1,392 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/models"
+	"percentman/openapi"
+)
+
+// settingsForm edits a models.RequestSettings value
+type settingsForm struct {
+	timeoutEntry        *widget.Entry
+	connectTimeoutEntry *widget.Entry
+	headerTimeoutEntry  *widget.Entry
+	maxDecodedEntry     *widget.Entry
+	keepAliveCheck      *widget.Check
+	http2Check          *widget.Check
+}
+
+// newSettingsForm creates the form widgets
+func newSettingsForm() *settingsForm {
+	return &settingsForm{
+		timeoutEntry:        newDurationEntry(),
+		connectTimeoutEntry: newDurationEntry(),
+		headerTimeoutEntry:  newDurationEntry(),
+		maxDecodedEntry:     newCountEntry(fmt.Sprintf("MB (empty = %d)", models.DefaultMaxDecodedSize>>20)),
+		keepAliveCheck:      widget.NewCheck("Keep-alive", nil),
+		http2Check:          widget.NewCheck("HTTP/2", nil),
+	}
+}
+
+// newDurationEntry creates an entry that accepts Go durations such as "1s" or "2m"
+func newDurationEntry() *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("e.g. 30s, 2m (empty = no limit)")
+	entry.Validator = func(s string) error {
+		_, err := parseDuration(s)
+		return err
+	}
+	return entry
+}
+
+// Build lays out the form
+func (f *settingsForm) Build() fyne.CanvasObject {
+	return container.New(layout.NewFormLayout(),
+		widget.NewLabel("Total timeout"), f.timeoutEntry,
+		widget.NewLabel("Connect timeout"), f.connectTimeoutEntry,
+		widget.NewLabel("Response header timeout"), f.headerTimeoutEntry,
+		widget.NewLabel("Max decoded body"), f.maxDecodedEntry,
+		widget.NewLabel("Connection"), container.NewHBox(f.keepAliveCheck, f.http2Check),
+	)
+}
+
+// Load fills the form from settings
+func (f *settingsForm) Load(s models.RequestSettings) {
+	f.timeoutEntry.SetText(formatDuration(s.Timeout))
+	f.connectTimeoutEntry.SetText(formatDuration(s.ConnectTimeout))
+	f.headerTimeoutEntry.SetText(formatDuration(s.ResponseHeaderTimeout))
+	f.maxDecodedEntry.SetText(formatCount(int(s.MaxDecodedSize >> 20)))
+	f.keepAliveCheck.SetChecked(!s.DisableKeepAlive)
+	f.http2Check.SetChecked(!s.DisableHTTP2)
+}
+
+// Read returns the settings entered in the form
+func (f *settingsForm) Read() (models.RequestSettings, error) {
+	var s models.RequestSettings
+	var err error
+
+	if s.Timeout, err = parseDuration(f.timeoutEntry.Text); err != nil {
+		return s, fmt.Errorf("total timeout: %w", err)
+	}
+	if s.ConnectTimeout, err = parseDuration(f.connectTimeoutEntry.Text); err != nil {
+		return s, fmt.Errorf("connect timeout: %w", err)
+	}
+	if s.ResponseHeaderTimeout, err = parseDuration(f.headerTimeoutEntry.Text); err != nil {
+		return s, fmt.Errorf("response header timeout: %w", err)
+	}
+	maxDecoded, err := parseCount(f.maxDecodedEntry.Text)
+	if err != nil {
+		return s, fmt.Errorf("max decoded body: %w", err)
+	}
+	s.MaxDecodedSize = int64(maxDecoded) << 20
+	s.DisableKeepAlive = !f.keepAliveCheck.Checked
+	s.DisableHTTP2 = !f.http2Check.Checked
+
+	return s, nil
+}
+
+// SetOnChanged calls fn whenever a form widget changes
+func (f *settingsForm) SetOnChanged(fn func()) {
+	for _, e := range []*widget.Entry{f.timeoutEntry, f.connectTimeoutEntry, f.headerTimeoutEntry, f.maxDecodedEntry} {
+		e.OnChanged = func(string) { fn() }
+	}
+	for _, c := range []*widget.Check{f.keepAliveCheck, f.http2Check} {
+		c.OnChanged = func(bool) { fn() }
+	}
+}
+
+// SetEnabled enables or disables all form widgets
+func (f *settingsForm) SetEnabled(enabled bool) {
+	for _, e := range []*widget.Entry{f.timeoutEntry, f.connectTimeoutEntry, f.headerTimeoutEntry, f.maxDecodedEntry} {
+		if enabled {
+			e.Enable()
+		} else {
+			e.Disable()
+		}
+	}
+	for _, c := range []*widget.Check{f.keepAliveCheck, f.http2Check} {
+		if enabled {
+			c.Enable()
+		} else {
+			c.Disable()
+		}
+	}
+}
+
+// parseDuration parses a duration, treating empty input as zero (no limit)
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration must not be negative")
+	}
+	return d, nil
+}
+
+// formatDuration formats a duration for editing, leaving zero empty
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// newCountEntry creates an entry that accepts a whole number, empty meaning none
+func newCountEntry(placeHolder string) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder(placeHolder)
+	entry.Validator = func(s string) error {
+		_, err := parseCount(s)
+		return err
+	}
+	return entry
+}
+
+// parseCount parses a whole number, treating empty input as zero (no limit)
+func parseCount(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a whole number", s)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("number must not be negative")
+	}
+	return n, nil
+}
+
+// formatCount formats a number for editing, leaving zero empty
+func formatCount(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// newListEntry creates an entry editing a list, one item per line
+func newListEntry(placeHolder string, items []string) *widget.Entry {
+	entry := widget.NewMultiLineEntry()
+	entry.SetPlaceHolder(placeHolder)
+	entry.SetMinRowsVisible(2)
+	entry.SetText(strings.Join(items, "\n"))
+	return entry
+}
+
+// parseLines returns the non-blank lines of text, trimmed
+func parseLines(text string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseVariables reads "name = value" lines, ignoring blank lines
+func parseVariables(text string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("variables line %d: expected name = value", i+1)
+		}
+		vars[name] = strings.TrimSpace(value)
+	}
+	return vars, nil
+}
+
+// formatVariables writes variables as sorted "name = value" lines
+func formatVariables(vars map[string]string) string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = name + " = " + vars[name]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ShowSettingsDialog shows the app-wide settings dialog
+func (a *App) ShowSettingsDialog() {
+	var popup *widget.PopUp
+
+	form := newSettingsForm()
+	form.Load(a.storage.GetSettings().Defaults)
+
+	variablesEntry := widget.NewMultiLineEntry()
+	variablesEntry.SetPlaceHolder("baseUrl = https://api.example.com")
+	variablesEntry.SetMinRowsVisible(4)
+	variablesEntry.SetText(formatVariables(a.storage.GetSettings().Variables))
+
+	specEntry := widget.NewEntry()
+	specEntry.SetPlaceHolder("Path to an OpenAPI document (empty = no validation)")
+	specEntry.SetText(a.storage.GetSettings().Spec)
+
+	collectionEntry := widget.NewEntry()
+	collectionEntry.SetPlaceHolder("Directory with one file per template (empty = templates.json)")
+	collectionEntry.SetText(a.storage.GetSettings().Collection)
+	browseBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err == nil && dir != nil {
+				collectionEntry.SetText(dir.Path())
+			}
+		}, a.window)
+	})
+
+	retention := a.storage.GetSettings().History
+	maxItemsEntry := newCountEntry("Items (empty = no limit)")
+	maxItemsEntry.SetText(formatCount(retention.MaxItems))
+	maxAgeEntry := newCountEntry("Days (empty = forever)")
+	maxAgeEntry.SetText(formatCount(retention.MaxAgeDays))
+	policy := a.storage.GetSettings().Redaction
+	redactHeadersEntry := newListEntry("Authorization", policy.Headers)
+	redactFieldsEntry := newListEntry("password\nuser.*.token", policy.Fields)
+	redactQueryEntry := newListEntry("api_key", policy.Query)
+	redactForm := container.New(layout.NewFormLayout(),
+		widget.NewLabel("Headers"), redactHeadersEntry,
+		widget.NewLabel("JSON fields"), redactFieldsEntry,
+		widget.NewLabel("Query parameters"), redactQueryEntry,
+	)
+
+	historyForm := container.New(layout.NewFormLayout(),
+		widget.NewLabel("Keep at most"), maxItemsEntry,
+		widget.NewLabel("Delete after"), maxAgeEntry,
+	)
+
+	titleLabel := widget.NewLabelWithStyle("Settings", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	defaultsLabel := widget.NewLabelWithStyle("Request defaults", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	variablesLabel := widget.NewLabelWithStyle("Variables ({{name}} in URLs, headers and bodies)", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	specLabel := widget.NewLabelWithStyle("Response validation", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	collectionLabel := widget.NewLabelWithStyle("Template collection", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	historyLabel := widget.NewLabelWithStyle("History", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	redactLabel := widget.NewLabelWithStyle("Mask in history and exports (one per line)", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+
+	errorLabel := widget.NewLabel("")
+	errorLabel.Importance = widget.DangerImportance
+	errorLabel.Hide()
+
+	saveBtn := widget.NewButton("Save", func() {
+		defaults, err := form.Read()
+		if err != nil {
+			errorLabel.SetText(err.Error())
+			errorLabel.Show()
+			return
+		}
+
+		vars, err := parseVariables(variablesEntry.Text)
+		if err != nil {
+			errorLabel.SetText(err.Error())
+			errorLabel.Show()
+			return
+		}
+
+		maxItems, err := parseCount(maxItemsEntry.Text)
+		if err != nil {
+			errorLabel.SetText("history items: " + err.Error())
+			errorLabel.Show()
+			return
+		}
+		maxAge, err := parseCount(maxAgeEntry.Text)
+		if err != nil {
+			errorLabel.SetText("history days: " + err.Error())
+			errorLabel.Show()
+			return
+		}
+
+		// Load a changed document before saving so a bad path can be corrected
+		settings := a.storage.GetSettings()
+		spec := a.spec
+		if path := strings.TrimSpace(specEntry.Text); path != settings.Spec {
+			spec = nil
+			if path != "" {
+				if spec, err = openapi.ParseFile(path); err != nil {
+					errorLabel.SetText("OpenAPI document: " + err.Error())
+					errorLabel.Show()
+					return
+				}
+			}
+		}
+
+		settings.Defaults = defaults
+		settings.Variables = vars
+		settings.Spec = strings.TrimSpace(specEntry.Text)
+		settings.Collection = strings.TrimSpace(collectionEntry.Text)
+		settings.History = models.HistoryRetention{MaxItems: maxItems, MaxAgeDays: maxAge}
+		settings.Redaction = models.RedactionPolicy{
+			Headers: parseLines(redactHeadersEntry.Text),
+			Fields:  parseLines(redactFieldsEntry.Text),
+			Query:   parseLines(redactQueryEntry.Text),
+		}
+		if err := a.SaveSettings(settings); err != nil {
+			errorLabel.SetText(err.Error())
+			errorLabel.Show()
+			return
+		}
+		a.spec = spec
+		popup.Hide()
+	})
+	saveBtn.Importance = widget.HighImportance
+
+	cancelBtn := widget.NewButton("Cancel", func() {
+		popup.Hide()
+	})
+
+	buttons := container.NewHBox(
+		layout.NewSpacer(),
+		cancelBtn,
+		saveBtn,
+	)
+
+	content := container.NewVBox(
+		titleLabel,
+		widget.NewSeparator(),
+		defaultsLabel,
+		form.Build(),
+		variablesLabel,
+		variablesEntry,
+		specLabel,
+		specEntry,
+		collectionLabel,
+		container.NewBorder(nil, nil, nil, browseBtn, collectionEntry),
+		historyLabel,
+		historyForm,
+		redactLabel,
+		redactForm,
+		errorLabel,
+		widget.NewSeparator(),
+		buttons,
+	)
+
+	popup = widget.NewModalPopUp(container.NewPadded(content), a.window.Canvas())
+	popup.Resize(fyne.NewSize(480, 0))
+	popup.Show()
+}
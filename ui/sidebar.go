@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -11,6 +12,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	"percentman/models"
+	"percentman/storage"
 )
 
 // Sidebar represents the left panel with templates and history
@@ -19,6 +21,13 @@ type Sidebar struct {
 
 	templatesContainer *fyne.Container
 	historyContainer   *fyne.Container
+
+	historyFilter *historyFilter
+	// historyPage is the page of history shown, counting from 0
+	historyPage int
+	pageLabel   *widget.Label
+	prevPageBtn *widget.Button
+	nextPageBtn *widget.Button
 }
 
 // NewSidebar creates a new sidebar
@@ -36,8 +45,17 @@ func (s *Sidebar) Build() fyne.CanvasObject {
 		widget.NewLabelWithStyle("Templates", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 	)
 
-	saveBtn := widget.NewButtonWithIcon("Save Current", theme.ContentAddIcon(), func() {
-		s.app.ShowSaveTemplateDialog()
+	saveBtn := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+		s.app.Save()
+	})
+	saveAsBtn := widget.NewButtonWithIcon("Save As", theme.ContentAddIcon(), func() {
+		s.app.ShowSaveAsDialog()
+	})
+	importBtn := widget.NewButtonWithIcon("Import", theme.DownloadIcon(), func() {
+		s.app.ShowImportDialog()
+	})
+	exportBtn := widget.NewButtonWithIcon("Export", theme.UploadIcon(), func() {
+		s.app.ShowExportHTTPDialog()
 	})
 
 	s.templatesContainer = container.NewVBox()
@@ -48,7 +66,7 @@ func (s *Sidebar) Build() fyne.CanvasObject {
 
 	templatesSection := container.NewBorder(
 		templatesTitle,
-		saveBtn,
+		container.NewGridWithColumns(2, saveBtn, saveAsBtn, importBtn, exportBtn),
 		nil, nil,
 		templatesScroll,
 	)
@@ -62,6 +80,26 @@ func (s *Sidebar) Build() fyne.CanvasObject {
 	clearBtn := widget.NewButtonWithIcon("Clear All", theme.DeleteIcon(), func() {
 		s.app.ClearHistory()
 	})
+	exportHARBtn := widget.NewButtonWithIcon("Export HAR", theme.UploadIcon(), func() {
+		s.app.ShowExportHARDialog()
+	})
+
+	var filterBar fyne.CanvasObject
+	s.historyFilter, filterBar = buildHistoryFilter(func() {
+		s.historyPage = 0
+		s.RefreshHistory()
+	})
+
+	s.pageLabel = widget.NewLabel("")
+	s.prevPageBtn = widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() {
+		s.historyPage--
+		s.RefreshHistory()
+	})
+	s.nextPageBtn = widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() {
+		s.historyPage++
+		s.RefreshHistory()
+	})
+	pager := container.NewBorder(nil, nil, s.prevPageBtn, s.nextPageBtn, container.NewCenter(s.pageLabel))
 
 	s.historyContainer = container.NewVBox()
 	s.RefreshHistory()
@@ -70,8 +108,8 @@ func (s *Sidebar) Build() fyne.CanvasObject {
 	historyScroll.SetMinSize(fyne.NewSize(200, 150))
 
 	historySection := container.NewBorder(
-		historyTitle,
-		clearBtn,
+		container.NewVBox(historyTitle, filterBar),
+		container.NewVBox(pager, container.NewGridWithColumns(2, clearBtn, exportHARBtn)),
 		nil, nil,
 		historyScroll,
 	)
@@ -92,11 +130,30 @@ func (s *Sidebar) RefreshTemplates() {
 	if len(templates) == 0 {
 		s.templatesContainer.Add(widget.NewLabel("No templates saved"))
 	} else {
+		// Top-level templates first, then one group per folder
+		var folders []string
+		byFolder := make(map[string][]models.Template)
 		for _, t := range templates {
-			template := t // capture for closure
-			item := s.createTemplateItem(&template)
-			s.templatesContainer.Add(item)
-			s.templatesContainer.Add(widget.NewSeparator())
+			if _, ok := byFolder[t.Folder]; !ok && t.Folder != "" {
+				folders = append(folders, t.Folder)
+			}
+			byFolder[t.Folder] = append(byFolder[t.Folder], t)
+		}
+		sort.Strings(folders)
+
+		for _, folder := range append([]string{""}, folders...) {
+			if folder != "" {
+				s.templatesContainer.Add(container.NewHBox(
+					widget.NewIcon(theme.FolderOpenIcon()),
+					widget.NewLabelWithStyle(folder, fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+				))
+			}
+			for _, t := range byFolder[folder] {
+				template := t // capture for closure
+				item := s.createTemplateItem(&template)
+				s.templatesContainer.Add(item)
+				s.templatesContainer.Add(widget.NewSeparator())
+			}
 		}
 	}
 
@@ -105,34 +162,60 @@ func (s *Sidebar) RefreshTemplates() {
 
 // createTemplateItem creates a template list item (name only, single line)
 func (s *Sidebar) createTemplateItem(t *models.Template) fyne.CanvasObject {
-	// Single line: Template name (bold) + delete button
+	// Single line: Template name (bold) + rename, duplicate, history and delete buttons
 	nameLabel := widget.NewLabelWithStyle(t.Name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
 	nameLabel.Truncation = fyne.TextTruncateEllipsis
 
+	renameBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
+		s.app.ShowRenameDialog(t)
+	})
+	renameBtn.Importance = widget.LowImportance
+	duplicateBtn := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		s.app.DuplicateTemplate(t.ID)
+	})
+	duplicateBtn.Importance = widget.LowImportance
+	historyBtn := widget.NewButtonWithIcon("", theme.HistoryIcon(), func() {
+		s.app.ShowRevisionsDialog(t.ID)
+	})
+	historyBtn.Importance = widget.LowImportance
 	deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
 		s.app.DeleteTemplate(t.ID)
 	})
 	deleteBtn.Importance = widget.LowImportance
 
-	content := container.NewBorder(nil, nil, nil, deleteBtn, nameLabel)
+	content := container.NewBorder(nil, nil, nil, container.NewHBox(renameBtn, duplicateBtn, historyBtn, deleteBtn), nameLabel)
 
 	// Make the whole row clickable with tooltip (full URL)
-	tooltipText := fmt.Sprintf("%s %s", t.Request.Method, t.Request.URL)
+	tooltipText := fmt.Sprintf("%s %s", methodLabel(&t.Request), t.Request.URL)
 	clickable := NewClickableContainer(content, func() {
-		s.app.LoadRequest(&t.Request)
+		s.app.LoadTemplate(t)
 	}, tooltipText, s.app.GetWindow())
 
 	return clickable
 }
 
-// RefreshHistory refreshes the history list
+// RefreshHistory shows the current page of the history selected by the search and filters
 func (s *Sidebar) RefreshHistory() {
 	s.historyContainer.RemoveAll()
 
-	history := s.app.GetStorage().GetHistory()
+	q := s.historyFilter.Query()
+	q.Offset = s.historyPage * historyPageSize
+	q.Limit = historyPageSize
+	history, total := s.app.GetStorage().SearchHistory(q)
+	// The history may have shrunk since the page was chosen
+	if len(history) == 0 && s.historyPage > 0 {
+		s.historyPage = max(total-1, 0) / historyPageSize
+		q.Offset = s.historyPage * historyPageSize
+		history, total = s.app.GetStorage().SearchHistory(q)
+	}
+	s.refreshPager(q.Offset, len(history), total)
 
 	if len(history) == 0 {
-		s.historyContainer.Add(widget.NewLabel("No history yet"))
+		if q == (storage.HistoryQuery{Offset: q.Offset, Limit: q.Limit}) {
+			s.historyContainer.Add(widget.NewLabel("No history yet"))
+		} else {
+			s.historyContainer.Add(widget.NewLabel("No matching requests"))
+		}
 	} else {
 		for _, h := range history {
 			item := h // capture for closure
@@ -145,11 +228,30 @@ func (s *Sidebar) RefreshHistory() {
 	s.historyContainer.Refresh()
 }
 
+// refreshPager shows which items of the total are listed and enables paging
+func (s *Sidebar) refreshPager(offset, count, total int) {
+	if count == 0 {
+		s.pageLabel.SetText("")
+	} else {
+		s.pageLabel.SetText(fmt.Sprintf("%d-%d of %d", offset+1, offset+count, total))
+	}
+	if offset > 0 {
+		s.prevPageBtn.Enable()
+	} else {
+		s.prevPageBtn.Disable()
+	}
+	if offset+count < total {
+		s.nextPageBtn.Enable()
+	} else {
+		s.nextPageBtn.Disable()
+	}
+}
+
 // createHistoryItem creates a history list item with 2-line layout
 func (s *Sidebar) createHistoryItem(h *models.HistoryItem) fyne.CanvasObject {
 	// Line 1: Method + Full URL
-	methodLabel := widget.NewLabelWithStyle(
-		h.Request.Method,
+	methodText := widget.NewLabelWithStyle(
+		methodLabel(&h.Request),
 		fyne.TextAlignLeading,
 		fyne.TextStyle{Bold: true},
 	)
@@ -158,12 +260,24 @@ func (s *Sidebar) createHistoryItem(h *models.HistoryItem) fyne.CanvasObject {
 	urlLabel := widget.NewLabel(h.Request.URL)
 	urlLabel.Truncation = fyne.TextTruncateEllipsis
 
-	line1 := container.NewBorder(nil, nil, methodLabel, nil, urlLabel)
+	line1 := container.NewBorder(nil, nil, methodText, nil, urlLabel)
 
 	// Line 2: Status code + response time
 	statusText := fmt.Sprintf("%d %s", h.Response.StatusCode, getStatusText(h.Response.StatusCode))
 	statusLabel := widget.NewLabel(statusText)
-	if h.Response.StatusCode >= 200 && h.Response.StatusCode < 300 {
+	if h.Response.Error != "" {
+		// The request got no response
+		statusLabel.SetText(errorKindLabel(h.Response.ErrorKind))
+		statusLabel.Importance = widget.DangerImportance
+	} else if h.Request.IsGRPC() {
+		// gRPC status codes are not HTTP codes; 0 means OK
+		statusLabel.SetText(h.Response.Status)
+		if h.Response.StatusCode == 0 {
+			statusLabel.Importance = widget.SuccessImportance
+		} else {
+			statusLabel.Importance = widget.DangerImportance
+		}
+	} else if h.Response.StatusCode >= 200 && h.Response.StatusCode < 300 {
 		statusLabel.Importance = widget.SuccessImportance
 	} else if h.Response.StatusCode >= 400 {
 		statusLabel.Importance = widget.DangerImportance
@@ -175,12 +289,24 @@ func (s *Sidebar) createHistoryItem(h *models.HistoryItem) fyne.CanvasObject {
 	timeLabel.Importance = widget.LowImportance
 
 	line2 := container.NewHBox(statusLabel, widget.NewLabel("-"), timeLabel)
+	if h.Response.Error != "" {
+		line2.Objects = append([]fyne.CanvasObject{widget.NewIcon(theme.ErrorIcon())}, line2.Objects...)
+	}
+	if h.Redacted {
+		line2.Add(widget.NewIcon(theme.VisibilityOffIcon()))
+	}
 
 	// Combined 2-line layout
 	content := container.NewVBox(line1, line2)
 
-	// Make clickable with tooltip (full URL)
-	tooltipText := fmt.Sprintf("%s %s", h.Request.Method, h.Request.URL)
+	// Make clickable with tooltip (full URL, and the error of failed requests)
+	tooltipText := fmt.Sprintf("%s %s", methodLabel(&h.Request), h.Request.URL)
+	if h.Response.Error != "" {
+		tooltipText += "\n" + h.Response.Error
+	}
+	if h.Redacted {
+		tooltipText += "\nSensitive values were masked"
+	}
 	clickable := NewClickableContainer(content, func() {
 		s.app.LoadRequest(&h.Request)
 	}, tooltipText, s.app.GetWindow())
@@ -188,6 +314,17 @@ func (s *Sidebar) createHistoryItem(h *models.HistoryItem) fyne.CanvasObject {
 	return clickable
 }
 
+// methodLabel returns the method shown for a request, or its type for WebSocket and gRPC
+func methodLabel(req *models.Request) string {
+	switch {
+	case req.IsWebSocket():
+		return "WS"
+	case req.IsGRPC():
+		return "gRPC"
+	}
+	return req.Method
+}
+
 // getStatusText returns a short status text for common status codes
 func getStatusText(code int) string {
 	switch code {
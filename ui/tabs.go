@@ -0,0 +1,383 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/models"
+	"percentman/storage"
+)
+
+// maxTabTitle limits the length of tab titles taken from URLs
+const maxTabTitle = 32
+
+// requestTab is an open request with its own last response
+type requestTab struct {
+	request *models.Request
+	// template is the saved version of the request, nil until it is saved
+	template *models.Template
+	// response is the last response received, nil until the request is sent
+	response *models.Response
+	item     *container.TabItem
+
+	// cancelStream stops the tab's event stream, if one is open
+	cancelStream context.CancelFunc
+	// cancelCall aborts the tab's gRPC call, if one is running
+	cancelCall context.CancelFunc
+}
+
+// blankRequest returns the request a new tab starts with
+func blankRequest() *models.Request {
+	req := models.NewRequest()
+	req.Headers = []models.Header{{Key: "Content-Type", Value: "application/json", Enabled: true}}
+	return req
+}
+
+// normalizeRequest returns req as the editor reads it back after loading it,
+// so that loading a request does not count as changing it
+func normalizeRequest(req *models.Request) *models.Request {
+	n := req.Clone()
+	if n.Method == "" {
+		n.Method = "GET"
+	}
+
+	n.Type = ""
+	n.Subprotocols = nil
+	n.GRPC = nil
+	switch {
+	case req.IsWebSocket():
+		n.Type = models.RequestTypeWebSocket
+		n.Subprotocols = splitList(strings.Join(req.Subprotocols, ", "))
+	case req.IsGRPC():
+		n.Type = models.RequestTypeGRPC
+		n.GRPC = &models.GRPCRequest{}
+		if g := req.GRPC; g != nil {
+			n.GRPC.ProtoFiles = splitList(strings.Join(g.ProtoFiles, ", "))
+			n.GRPC.ImportPaths = splitList(strings.Join(g.ImportPaths, ", "))
+			n.GRPC.Plaintext = g.Plaintext
+			if g.Service != "" && g.Method != "" {
+				n.GRPC.Service, n.GRPC.Method = g.Service, g.Method
+			}
+		}
+	}
+
+	// gRPC messages are always raw JSON
+	n.BodyMode = ""
+	n.GraphQL = nil
+	if req.BodyMode == models.BodyModeGraphQL && !req.IsGRPC() {
+		n.BodyMode = models.BodyModeGraphQL
+		n.GraphQL = &models.GraphQLBody{}
+		if req.GraphQL != nil {
+			*n.GraphQL = *req.GraphQL
+		}
+	}
+
+	n.Headers = []models.Header{}
+	for _, h := range req.Headers {
+		if h.Key != "" {
+			n.Headers = append(n.Headers, h)
+		}
+	}
+	return n
+}
+
+// dirty reports whether the request differs from its saved template, or,
+// for requests that were never saved, from a blank request
+func (t *requestTab) dirty() bool {
+	saved := blankRequest()
+	if t.template != nil {
+		saved = &t.template.Request
+	}
+	return !sameRequest(normalizeRequest(t.request), normalizeRequest(saved))
+}
+
+// blank reports whether the tab holds nothing worth keeping, so opening a
+// request can reuse it
+func (t *requestTab) blank() bool {
+	return t.template == nil && t.response == nil && !t.dirty()
+}
+
+// active reports whether the tab has an event stream open or a gRPC call running
+func (t *requestTab) active() bool {
+	return t.cancelStream != nil || t.cancelCall != nil
+}
+
+// stop ends the tab's event stream and gRPC call
+func (t *requestTab) stop() {
+	if t.cancelStream != nil {
+		t.cancelStream()
+	}
+	if t.cancelCall != nil {
+		t.cancelCall()
+	}
+}
+
+// name returns the template name, or describes a request that was never saved
+func (t *requestTab) name() string {
+	switch {
+	case t.template != nil:
+		return t.template.Name
+	case t.request.URL == "":
+		return "New Request"
+	}
+	name := []rune(methodLabel(t.request) + " " + t.request.URL)
+	if len(name) > maxTabTitle {
+		return string(name[:maxTabTitle-1]) + "…"
+	}
+	return string(name)
+}
+
+// title returns the tab label, marked while there are unsaved changes
+func (t *requestTab) title() string {
+	if t.dirty() {
+		return "● " + t.name()
+	}
+	return t.name()
+}
+
+// buildTabBar creates the bar of open requests and restores the tabs of the last session
+func (a *App) buildTabBar() fyne.CanvasObject {
+	a.tabBar = container.NewDocTabs()
+	a.tabBar.CreateTab = func() *container.TabItem {
+		return a.newTab(blankRequest(), nil).item
+	}
+	a.tabBar.OnSelected = func(item *container.TabItem) {
+		a.selectTab(a.tabOf(item))
+	}
+	a.tabBar.CloseIntercept = func(item *container.TabItem) {
+		a.closeTab(a.tabOf(item))
+	}
+
+	a.restoreSession()
+	return a.tabBar
+}
+
+// newTab creates a tab for req, which the caller adds to the tab bar
+func (a *App) newTab(req *models.Request, t *models.Template) *requestTab {
+	tab := &requestTab{request: req.Clone(), template: t}
+	// The editor is shared, so tabs have no content of their own
+	tab.item = container.NewTabItem(tab.title(), layout.NewSpacer())
+	a.tabs = append(a.tabs, tab)
+	return tab
+}
+
+// tabOf returns the tab shown by item
+func (a *App) tabOf(item *container.TabItem) *requestTab {
+	for _, tab := range a.tabs {
+		if tab.item == item {
+			return tab
+		}
+	}
+	return nil
+}
+
+// openTab shows req in a tab of its own, reusing the current tab if it is blank
+func (a *App) openTab(req *models.Request, t *models.Template) {
+	if a.tab != nil && a.tab.blank() {
+		a.tab.stop()
+		a.DisconnectWebSocket()
+		a.tab.request = req.Clone()
+		a.tab.template = t
+		a.showTab()
+		a.saveSession()
+		return
+	}
+
+	tab := a.newTab(req, t)
+	a.tabBar.Append(tab.item)
+	a.tabBar.Select(tab.item)
+}
+
+// selectTab shows tab in the editor, keeping the edits of the previous tab.
+// Streams and calls go on in the background; the WebSocket session, which
+// has a panel of its own, ends.
+func (a *App) selectTab(tab *requestTab) {
+	if tab == nil || tab == a.tab {
+		return
+	}
+	if a.tab != nil {
+		a.syncRequest()
+	}
+	a.DisconnectWebSocket()
+	a.tab = tab
+	a.showTab()
+	a.saveSession()
+}
+
+// showTab loads the current tab into the editor and shows its last response
+func (a *App) showTab() {
+	a.request.LoadRequest(a.tab.request)
+	a.websocket.Clear()
+	if a.tab.request.IsWebSocket() {
+		a.websocket.SetDraft(a.tab.request.Body)
+	}
+	a.response.Clear()
+	switch {
+	case a.tab.cancelStream != nil:
+		a.response.StartStream()
+	case a.tab.cancelCall != nil:
+		a.response.StartCall()
+	case a.tab.response != nil:
+		a.response.DisplayResponse(a.tab.response)
+	}
+	a.request.SetActive(a.tab.active())
+	a.refreshTab(a.tab)
+}
+
+// stopSessions ends the streams and calls of every tab and the WebSocket session
+func (a *App) stopSessions() {
+	for _, tab := range a.tabs {
+		tab.stop()
+	}
+	a.DisconnectWebSocket()
+}
+
+// refreshTab updates the label of tab after its request or template changed
+func (a *App) refreshTab(tab *requestTab) {
+	if title := tab.title(); tab.item.Text != title {
+		tab.item.Text = title
+		a.tabBar.Refresh()
+	}
+}
+
+// onRequestEdited keeps the current tab in step with the editor
+func (a *App) onRequestEdited() {
+	if a.tab == nil {
+		return
+	}
+	a.syncRequest()
+	a.refreshTab(a.tab)
+}
+
+// closeTab closes tab, first asking whether to save unsaved changes
+func (a *App) closeTab(tab *requestTab) {
+	if tab == nil {
+		return
+	}
+	if tab == a.tab {
+		a.syncRequest()
+	}
+	if !tab.dirty() {
+		a.removeTab(tab)
+		return
+	}
+
+	var popup *widget.PopUp
+	titleLabel := widget.NewLabelWithStyle("Unsaved Changes", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	message := widget.NewLabel(fmt.Sprintf("Save the changes to %q before closing it?", tab.name()))
+
+	saveBtn := widget.NewButton("Save", func() {
+		popup.Hide()
+		a.saveTab(tab, func() {
+			a.removeTab(tab)
+		})
+	})
+	saveBtn.Importance = widget.HighImportance
+	discardBtn := widget.NewButton("Discard", func() {
+		popup.Hide()
+		a.removeTab(tab)
+	})
+	cancelBtn := widget.NewButton("Cancel", func() {
+		popup.Hide()
+	})
+
+	content := container.NewVBox(
+		titleLabel,
+		widget.NewSeparator(),
+		message,
+		widget.NewSeparator(),
+		container.NewHBox(layout.NewSpacer(), cancelBtn, discardBtn, saveBtn),
+	)
+	popup = widget.NewModalPopUp(container.NewPadded(content), a.window.Canvas())
+	popup.Show()
+}
+
+// removeTab closes tab without saving. Closing the last tab opens a blank one.
+func (a *App) removeTab(tab *requestTab) {
+	for i, t := range a.tabs {
+		if t == tab {
+			a.tabs = append(a.tabs[:i], a.tabs[i+1:]...)
+			break
+		}
+	}
+	tab.stop()
+	if tab == a.tab {
+		a.DisconnectWebSocket()
+		a.tab = nil
+	}
+
+	a.tabBar.Remove(tab.item)
+	if len(a.tabs) == 0 {
+		tab := a.newTab(blankRequest(), nil)
+		a.tabBar.Append(tab.item)
+	}
+	// Removing the selected tab moves the selection without notifying
+	a.selectTab(a.tabOf(a.tabBar.Selected()))
+	a.saveSession()
+}
+
+// restoreSession reopens the tabs that were open when the workspace was last
+// used, or a blank tab. Tabs follow the current version of their template.
+func (a *App) restoreSession() {
+	session, err := storage.LoadSession(a.storage.Dir())
+	if err != nil {
+		log.Printf("Failed to restore open requests: %v", err)
+	}
+
+	var items []*container.TabItem
+	for _, st := range session.Tabs {
+		var t *models.Template
+		if st.TemplateID != "" {
+			t = a.storage.GetTemplateByID(st.TemplateID)
+		}
+		req := st.Request
+		items = append(items, a.newTab(&req, t).item)
+	}
+	if len(items) == 0 {
+		items = append(items, a.newTab(blankRequest(), nil).item)
+	}
+
+	a.tabBar.SetItems(items)
+	if session.Active > 0 && session.Active < len(items) {
+		a.tabBar.SelectIndex(session.Active)
+	}
+	// The first tab is selected without a notification
+	a.selectTab(a.tabOf(a.tabBar.Selected()))
+}
+
+// SaveSession records the open tabs, so they are restored on the next start
+func (a *App) SaveSession() {
+	if a.tab != nil {
+		a.syncRequest()
+	}
+	a.saveSession()
+}
+
+// saveSession records the open tabs as they were last synced from the editor
+func (a *App) saveSession() {
+	if a.tabBar == nil {
+		return
+	}
+	session := models.Session{Active: a.tabBar.SelectedIndex()}
+	for _, item := range a.tabBar.Items {
+		tab := a.tabOf(item)
+		if tab == nil {
+			continue
+		}
+		st := models.SessionTab{Request: *tab.request}
+		if tab.template != nil {
+			st.TemplateID = tab.template.ID
+		}
+		session.Tabs = append(session.Tabs, st)
+	}
+	if err := storage.SaveSession(a.storage.Dir(), session); err != nil {
+		log.Printf("Failed to save open requests: %v", err)
+	}
+}
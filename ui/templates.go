@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/models"
+	"percentman/storage"
+)
+
+// Save updates the template the current request was opened from, or asks for
+// a name if the request was never saved
+func (a *App) Save() {
+	a.syncRequest()
+	a.saveTab(a.tab, nil)
+}
+
+// saveTab saves the request of tab to its template, or asks for a name, and
+// calls onSaved once it is saved
+func (a *App) saveTab(tab *requestTab, onSaved func()) {
+	if tab.template == nil {
+		a.saveTabAs(tab, "", onSaved)
+		return
+	}
+	a.updateTemplate(tab, tab.template.Revision, onSaved)
+}
+
+// updateTemplate saves the request of tab over its template. A revision other
+// than 0 must still be the stored one; otherwise the user decides whether to
+// overwrite the changes made elsewhere.
+func (a *App) updateTemplate(tab *requestTab, revision int, onSaved func()) {
+	t, err := a.storage.UpdateTemplate(tab.template.ID, tab.request, revision)
+	switch {
+	case errors.Is(err, storage.ErrTemplateChanged):
+		a.showSaveConflict(tab, onSaved)
+	case errors.Is(err, storage.ErrTemplateNotFound):
+		// Deleted elsewhere; saving recreates it
+		name := tab.template.Name
+		tab.template = nil
+		a.refreshTab(tab)
+		a.saveTabAs(tab, name, onSaved)
+	case err != nil:
+		dialog.ShowError(err, a.window)
+	default:
+		a.templateSaved(tab, t)
+		if onSaved != nil {
+			onSaved()
+		}
+	}
+}
+
+// saveTabAs asks for a name, suggesting name, and saves the request of tab as
+// a new template called that, then calls onSaved
+func (a *App) saveTabAs(tab *requestTab, name string, onSaved func()) {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("Enter template name")
+	entry.SetText(name)
+
+	showSaveDialog(a.window, "Save as Template", entry, func(name string) {
+		save := func() {
+			if err := a.saveTabNamed(tab, name); err != nil {
+				dialog.ShowError(err, a.window)
+				return
+			}
+			if onSaved != nil {
+				onSaved()
+			}
+		}
+
+		// Saving under the name of a template, the tab's own included, replaces it
+		if a.storage.TemplateNameExists(name) {
+			dialog.ShowConfirm("Replace Template",
+				fmt.Sprintf("A template named %q already exists. Replace it with this request?", name),
+				func(ok bool) {
+					if ok {
+						save()
+					}
+				}, a.window)
+			return
+		}
+		save()
+	})
+}
+
+// showSaveConflict asks what to do when the template of tab was saved
+// elsewhere since it was opened
+func (a *App) showSaveConflict(tab *requestTab, onSaved func()) {
+	var popup *widget.PopUp
+
+	titleLabel := widget.NewLabelWithStyle("Template Changed", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	message := widget.NewLabel(fmt.Sprintf("%q was changed elsewhere since you opened it.\nOverwrite those changes, or save your request as a copy?", tab.template.Name))
+
+	overwriteBtn := widget.NewButton("Overwrite", func() {
+		popup.Hide()
+		a.updateTemplate(tab, 0, onSaved)
+	})
+	overwriteBtn.Importance = widget.HighImportance
+	copyBtn := widget.NewButton("Save as Copy", func() {
+		popup.Hide()
+		a.saveTabAs(tab, tab.template.Name+" copy", onSaved)
+	})
+	cancelBtn := widget.NewButton("Cancel", func() {
+		popup.Hide()
+	})
+
+	content := container.NewVBox(
+		titleLabel,
+		widget.NewSeparator(),
+		message,
+		widget.NewSeparator(),
+		container.NewHBox(layout.NewSpacer(), cancelBtn, copyBtn, overwriteBtn),
+	)
+	popup = widget.NewModalPopUp(container.NewPadded(content), a.window.Canvas())
+	popup.Show()
+}
+
+// templateSaved makes t the saved version of tab. Other tabs showing the
+// template follow it unless they have edits of their own.
+func (a *App) templateSaved(tab *requestTab, t *models.Template) {
+	saved := *t
+	tab.template = &saved
+	for _, other := range a.tabs {
+		if other != tab && other.template != nil && other.template.ID == t.ID && !other.dirty() {
+			latest := *t
+			a.reloadOpenTemplate(other, &latest)
+		}
+	}
+	a.refreshTab(tab)
+	a.sidebar.RefreshTemplates()
+	a.saveSession()
+}
+
+// buildMainMenu creates the menu holding the save actions and their shortcuts,
+// which work while typing in the editor
+func (a *App) buildMainMenu() *fyne.MainMenu {
+	save := fyne.NewMenuItem("Save", a.Save)
+	save.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyS, Modifier: fyne.KeyModifierShortcutDefault}
+	saveAs := fyne.NewMenuItem("Save As...", a.ShowSaveAsDialog)
+	saveAs.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyS, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+	return fyne.NewMainMenu(fyne.NewMenu("File", save, saveAs))
+}
+
+// ShowRenameDialog asks for a new name for t
+func (a *App) ShowRenameDialog(t *models.Template) {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("Enter template name")
+	entry.SetText(t.Name)
+
+	showSaveDialog(a.window, "Rename Template", entry, func(name string) {
+		if err := a.RenameTemplate(t.ID, name); err != nil {
+			dialog.ShowError(err, a.window)
+		}
+	})
+}
+
+// RenameTemplate renames a template. Open tabs keep their edits.
+func (a *App) RenameTemplate(id, name string) error {
+	renamed, err := a.storage.RenameTemplate(id, name)
+	if err != nil {
+		return err
+	}
+	for _, tab := range a.tabs {
+		if tab.template == nil || tab.template.ID != id {
+			continue
+		}
+		tab.template.Name = renamed.Name
+		// Only the name changed, so a tab holding the latest request is up to date
+		if sameRequest(&tab.template.Request, &renamed.Request) {
+			tab.template.Revision = renamed.Revision
+		}
+		a.refreshTab(tab)
+	}
+	a.sidebar.RefreshTemplates()
+	a.saveSession()
+	return nil
+}
+
+// DuplicateTemplate saves a copy of a template and opens it
+func (a *App) DuplicateTemplate(id string) {
+	copied, err := a.storage.DuplicateTemplate(id)
+	if err != nil {
+		dialog.ShowError(err, a.window)
+		return
+	}
+	a.sidebar.RefreshTemplates()
+	a.LoadTemplate(copied)
+}
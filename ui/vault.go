@@ -0,0 +1,273 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/models"
+)
+
+// secretMask is shown instead of secret values
+const secretMask = "••••••••"
+
+// variables returns the values substituted for {{name}} placeholders. With
+// withSecrets, the secrets of an unlocked vault are included as secret:name.
+func (a *App) variables(withSecrets bool) map[string]string {
+	vars := make(map[string]string)
+	for name, value := range a.storage.GetSettings().Variables {
+		vars[name] = value
+	}
+	if withSecrets && !a.vault.Locked() {
+		secrets, _ := a.vault.Values()
+		for name, value := range secrets {
+			vars[models.SecretPrefix+name] = value
+		}
+	}
+	return vars
+}
+
+// addHistory records a sent request. Secret values the server echoed back are
+// replaced by their placeholders; the storage masks denylisted fields.
+func (a *App) addHistory(req *models.Request, resp *models.Response) {
+	if !a.vault.Locked() {
+		if secrets, err := a.vault.Values(); err == nil && len(secrets) > 0 {
+			resp = maskSecrets(resp, secrets)
+		}
+	}
+	a.storage.AddHistory(req, resp)
+	a.sidebar.RefreshHistory()
+}
+
+// maskSecrets returns a copy of resp with secret values replaced by their placeholders
+func maskSecrets(resp *models.Response, secrets map[string]string) *models.Response {
+	var pairs []string
+	for name, value := range secrets {
+		if value != "" {
+			pairs = append(pairs, value, "{{"+models.SecretPrefix+name+"}}")
+		}
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	masked := *resp
+	masked.Body = replacer.Replace(resp.Body)
+	masked.Error = replacer.Replace(resp.Error)
+	masked.Headers = make(map[string]string, len(resp.Headers))
+	for key, value := range resp.Headers {
+		masked.Headers[key] = replacer.Replace(value)
+	}
+	return &masked
+}
+
+// ShowVaultDialog shows the secrets, after creating or unlocking the vault if needed
+func (a *App) ShowVaultDialog() {
+	a.unlockVault(a.showSecretsDialog)
+}
+
+// unlockVault calls onUnlock once the vault is unlocked, asking for its
+// passphrase, or for a new one if it has not been created yet
+func (a *App) unlockVault(onUnlock func()) {
+	switch {
+	case !a.vault.Created():
+		a.showPassphraseDialog(true, onUnlock)
+	case a.vault.Locked():
+		a.showPassphraseDialog(false, onUnlock)
+	default:
+		onUnlock()
+	}
+}
+
+// showPassphraseDialog asks for the master passphrase, or a key file, to
+// create or unlock the vault
+func (a *App) showPassphraseDialog(create bool, onUnlock func()) {
+	var popup *widget.PopUp
+
+	title := "Unlock Secrets"
+	intro := "Enter the master passphrase, or choose the key file, of this workspace's secrets."
+	if create {
+		title = "Create Secrets Vault"
+		intro = "Secrets are encrypted with a master passphrase or the content of a key file.\nThey cannot be recovered without it."
+	}
+	titleLabel := widget.NewLabelWithStyle(title, fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
+	passEntry := widget.NewPasswordEntry()
+	passEntry.SetPlaceHolder("Master passphrase")
+	confirmEntry := widget.NewPasswordEntry()
+	confirmEntry.SetPlaceHolder("Repeat passphrase")
+	if !create {
+		confirmEntry.Hide()
+	}
+
+	errorLabel := widget.NewLabel("")
+	errorLabel.Importance = widget.DangerImportance
+	errorLabel.Hide()
+
+	// use creates or unlocks the vault with passphrase
+	use := func(passphrase []byte) {
+		var err error
+		if create {
+			err = a.vault.Create(passphrase)
+		} else {
+			err = a.vault.Unlock(passphrase)
+		}
+		if err != nil {
+			errorLabel.SetText(err.Error())
+			errorLabel.Show()
+			return
+		}
+		popup.Hide()
+		onUnlock()
+	}
+
+	okText := "Unlock"
+	if create {
+		okText = "Create"
+	}
+	okBtn := widget.NewButton(okText, func() {
+		if create && passEntry.Text != confirmEntry.Text {
+			errorLabel.SetText("The passphrases do not match")
+			errorLabel.Show()
+			return
+		}
+		use([]byte(passEntry.Text))
+	})
+	okBtn.Importance = widget.HighImportance
+	passEntry.OnSubmitted = func(string) {
+		if !create {
+			okBtn.OnTapped()
+		}
+	}
+
+	keyFileBtn := widget.NewButtonWithIcon("Key File...", theme.FileIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(err, a.window)
+				return
+			}
+			// Editors often add a final newline that is not part of the key
+			use(bytes.TrimRight(data, "\r\n"))
+		}, a.window)
+	})
+
+	cancelBtn := widget.NewButton("Cancel", func() {
+		popup.Hide()
+	})
+
+	content := container.NewVBox(
+		titleLabel,
+		widget.NewSeparator(),
+		widget.NewLabel(intro),
+		passEntry,
+		confirmEntry,
+		errorLabel,
+		widget.NewSeparator(),
+		container.NewHBox(keyFileBtn, layout.NewSpacer(), cancelBtn, okBtn),
+	)
+
+	popup = widget.NewModalPopUp(container.NewPadded(content), a.window.Canvas())
+	popup.Resize(fyne.NewSize(440, 0))
+	popup.Show()
+	a.window.Canvas().Focus(passEntry)
+}
+
+// showSecretsDialog lists the secrets of the unlocked vault and lets the user
+// add, replace and delete them. Values are never shown.
+func (a *App) showSecretsDialog() {
+	var popup *widget.PopUp
+
+	titleLabel := widget.NewLabelWithStyle("Secrets", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	hintLabel := widget.NewLabel("Use a secret in a request as {{secret:name}}.")
+
+	errorLabel := widget.NewLabel("")
+	errorLabel.Importance = widget.DangerImportance
+	errorLabel.Hide()
+	showErr := func(err error) {
+		errorLabel.SetText(err.Error())
+		errorLabel.Show()
+	}
+
+	list := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		list.RemoveAll()
+		names := a.vault.Names()
+		if len(names) == 0 {
+			list.Add(widget.NewLabel("No secrets stored"))
+		}
+		for _, name := range names {
+			copyBtn := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+				a.fyneApp.Clipboard().SetContent("{{" + models.SecretPrefix + name + "}}")
+			})
+			copyBtn.Importance = widget.LowImportance
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				if err := a.vault.Delete(name); err != nil {
+					showErr(err)
+					return
+				}
+				refresh()
+			})
+			deleteBtn.Importance = widget.LowImportance
+
+			nameLabel := widget.NewLabelWithStyle(name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			list.Add(container.NewBorder(nil, nil, nameLabel, container.NewHBox(copyBtn, deleteBtn), widget.NewLabel(secretMask)))
+		}
+		list.Refresh()
+	}
+	refresh()
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Name")
+	valueEntry := widget.NewPasswordEntry()
+	valueEntry.SetPlaceHolder("Value")
+	saveBtn := widget.NewButtonWithIcon("Save", theme.ContentAddIcon(), func() {
+		if err := a.vault.Set(strings.TrimSpace(nameEntry.Text), valueEntry.Text); err != nil {
+			showErr(err)
+			return
+		}
+		errorLabel.Hide()
+		nameEntry.SetText("")
+		valueEntry.SetText("")
+		refresh()
+	})
+
+	listScroll := container.NewVScroll(list)
+	listScroll.SetMinSize(fyne.NewSize(0, 160))
+
+	lockBtn := widget.NewButton("Lock", func() {
+		a.vault.Lock()
+		popup.Hide()
+	})
+	closeBtn := widget.NewButton("Close", func() {
+		popup.Hide()
+	})
+	closeBtn.Importance = widget.HighImportance
+
+	content := container.NewVBox(
+		titleLabel,
+		widget.NewSeparator(),
+		hintLabel,
+		listScroll,
+		widget.NewSeparator(),
+		container.NewGridWithColumns(2, nameEntry, valueEntry),
+		container.NewHBox(layout.NewSpacer(), saveBtn),
+		errorLabel,
+		widget.NewSeparator(),
+		container.NewHBox(lockBtn, layout.NewSpacer(), closeBtn),
+	)
+
+	popup = widget.NewModalPopUp(container.NewPadded(content), a.window.Canvas())
+	popup.Resize(fyne.NewSize(480, 0))
+	popup.Show()
+}
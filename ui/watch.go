@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"percentman/models"
+	"percentman/storage"
+)
+
+// watchStorage follows changes other programs make to the workspace,
+// replacing the watch of any previous workspace
+func (a *App) watchStorage() {
+	if a.stopWatch != nil {
+		a.stopWatch()
+		a.stopWatch = nil
+	}
+	stop, err := a.storage.Watch(func(changes storage.Changes) {
+		fyne.Do(func() {
+			a.onStorageChanged(changes)
+		})
+	})
+	if err != nil {
+		log.Printf("Failed to watch %s: %v", a.storage.Dir(), err)
+		return
+	}
+	a.stopWatch = stop
+}
+
+// onStorageChanged refreshes the sidebar after templates or history changed on disk
+func (a *App) onStorageChanged(changes storage.Changes) {
+	if changes.History {
+		a.sidebar.RefreshHistory()
+	}
+	if changes.Templates {
+		a.sidebar.RefreshTemplates()
+		a.checkOpenTemplates()
+	}
+}
+
+// LoadTemplate shows a template, switching to its tab if it is already open
+func (a *App) LoadTemplate(t *models.Template) {
+	for _, tab := range a.tabs {
+		if tab.template != nil && tab.template.ID == t.ID {
+			a.tabBar.Select(tab.item)
+			return
+		}
+	}
+	open := *t
+	a.openTab(&t.Request, &open)
+}
+
+// checkOpenTemplates compares the templates of the open tabs with their
+// versions on disk
+func (a *App) checkOpenTemplates() {
+	if a.tab != nil {
+		a.syncRequest()
+	}
+	for _, tab := range a.tabs {
+		if tab.template != nil {
+			a.checkOpenTemplate(tab)
+		}
+	}
+}
+
+// checkOpenTemplate compares the template of tab with its version on disk. An
+// unedited request follows the new version; edits are only replaced if the
+// user agrees.
+func (a *App) checkOpenTemplate(tab *requestTab) {
+	latest := a.storage.GetTemplateByID(tab.template.ID)
+	if latest == nil {
+		dialog.ShowInformation("Template removed",
+			fmt.Sprintf("%q was removed outside percentman. Your request is kept; save it to recreate the template.", tab.template.Name),
+			a.window)
+		tab.template = nil
+		a.refreshTab(tab)
+		return
+	}
+	if sameRequest(&latest.Request, &tab.template.Request) {
+		tab.template = latest
+		a.refreshTab(tab)
+		return
+	}
+
+	if !tab.dirty() {
+		a.reloadOpenTemplate(tab, latest)
+		return
+	}
+
+	dialog.ShowConfirm("Template changed on disk",
+		fmt.Sprintf("%q was changed outside percentman while you were editing it. Load the new version and discard your edits?", latest.Name),
+		func(load bool) {
+			if load {
+				a.reloadOpenTemplate(tab, latest)
+				return
+			}
+			// Keep the edits; the next conflict is judged against this version
+			tab.template = latest
+			a.refreshTab(tab)
+		}, a.window)
+}
+
+// reloadOpenTemplate shows a newer version of the template of tab, keeping the response
+func (a *App) reloadOpenTemplate(tab *requestTab, t *models.Template) {
+	tab.request = t.Request.Clone()
+	tab.template = t
+	if tab == a.tab {
+		a.request.LoadRequest(tab.request)
+	}
+	a.refreshTab(tab)
+}
+
+// sameRequest reports whether two requests would be saved identically
+func sameRequest(a, b *models.Request) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(x) == string(y)
+}
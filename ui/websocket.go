@@ -0,0 +1,261 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	httpclient "percentman/http"
+	"percentman/models"
+)
+
+// Message kinds offered by the composer
+const (
+	messageKindText   = "Text"
+	messageKindJSON   = "JSON"
+	messageKindBinary = "Binary (hex)"
+)
+
+// WebSocketPanel shows the message log and composer of a WebSocket session
+type WebSocketPanel struct {
+	app *App
+
+	statusLabel      *widget.Label
+	logList          *widget.List
+	messages         []models.WebSocketMessage
+	kindSelect       *widget.Select
+	composer         *widget.Entry
+	closeCodeEntry   *widget.Entry
+	closeReasonEntry *widget.Entry
+	sendBtn          *widget.Button
+	pingBtn          *widget.Button
+	closeBtn         *widget.Button
+}
+
+// NewWebSocketPanel creates a new WebSocket panel
+func NewWebSocketPanel(app *App) *WebSocketPanel {
+	return &WebSocketPanel{
+		app: app,
+	}
+}
+
+// Build creates the WebSocket panel UI
+func (w *WebSocketPanel) Build() fyne.CanvasObject {
+	w.statusLabel = widget.NewLabel("Status: Disconnected")
+
+	statusBar := container.NewHBox(
+		widget.NewIcon(theme.InfoIcon()),
+		widget.NewLabelWithStyle("Messages", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewSeparator(),
+		w.statusLabel,
+		layout.NewSpacer(),
+		widget.NewButtonWithIcon("Clear", theme.ContentClearIcon(), func() {
+			w.messages = nil
+			w.logList.Refresh()
+		}),
+	)
+
+	// Message log
+	w.logList = widget.NewList(
+		func() int {
+			return len(w.messages)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			label := obj.(*widget.Label)
+			m := w.messages[id]
+			label.SetText(messageText(m))
+			switch {
+			case m.Type == httpclient.MessageError:
+				label.Importance = widget.DangerImportance
+			case m.Direction == models.DirectionSent:
+				label.Importance = widget.MediumImportance
+			default:
+				label.Importance = widget.SuccessImportance
+			}
+			label.Refresh()
+		},
+	)
+	w.logList.OnSelected = func(id widget.ListItemID) {
+		w.logList.Unselect(id)
+		w.showMessage(w.messages[id])
+	}
+
+	// Composer
+	w.kindSelect = widget.NewSelect([]string{messageKindText, messageKindJSON, messageKindBinary}, nil)
+	w.kindSelect.SetSelected(messageKindText)
+
+	w.composer = widget.NewMultiLineEntry()
+	w.composer.SetPlaceHolder("Message to send")
+	w.composer.SetMinRowsVisible(3)
+
+	w.sendBtn = widget.NewButtonWithIcon("Send", theme.MailSendIcon(), func() {
+		if err := w.app.SendWebSocketMessage(w.kindSelect.Selected, w.composer.Text); err != nil {
+			dialog.ShowError(err, w.app.GetWindow())
+		}
+	})
+	w.sendBtn.Importance = widget.HighImportance
+
+	w.pingBtn = widget.NewButton("Ping", func() {
+		if err := w.app.PingWebSocket(); err != nil {
+			dialog.ShowError(err, w.app.GetWindow())
+		}
+	})
+
+	w.closeCodeEntry = widget.NewEntry()
+	w.closeCodeEntry.SetText("1000")
+	w.closeCodeEntry.Validator = func(s string) error {
+		_, err := parseCloseCode(s)
+		return err
+	}
+	w.closeReasonEntry = widget.NewEntry()
+	w.closeReasonEntry.SetPlaceHolder("Close reason")
+
+	w.closeBtn = widget.NewButton("Close", func() {
+		code, err := parseCloseCode(w.closeCodeEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, w.app.GetWindow())
+			return
+		}
+		w.app.CloseWebSocket(code, w.closeReasonEntry.Text)
+	})
+
+	controls := container.NewBorder(
+		nil, nil,
+		container.NewHBox(w.kindSelect, w.sendBtn, w.pingBtn),
+		container.NewHBox(widget.NewLabel("Code"), w.closeCodeEntry, w.closeBtn),
+		w.closeReasonEntry,
+	)
+	composerSection := container.NewBorder(nil, controls, nil, nil, w.composer)
+
+	w.SetConnected(false)
+
+	split := container.NewVSplit(w.logList, composerSection)
+	split.SetOffset(0.7)
+
+	return container.NewBorder(statusBar, nil, nil, nil, split)
+}
+
+// SetConnecting shows that a handshake is in progress
+func (w *WebSocketPanel) SetConnecting() {
+	w.statusLabel.SetText("Status: Connecting...")
+	w.statusLabel.Importance = widget.MediumImportance
+	w.statusLabel.Refresh()
+}
+
+// SetConnected enables the composer while a connection is open
+func (w *WebSocketPanel) SetConnected(connected bool) {
+	for _, btn := range []*widget.Button{w.sendBtn, w.pingBtn, w.closeBtn} {
+		if connected {
+			btn.Enable()
+		} else {
+			btn.Disable()
+		}
+	}
+}
+
+// ShowConnected displays the handshake result of an open connection
+func (w *WebSocketPanel) ShowConnected(resp *models.Response, subprotocol string) {
+	text := "Status: Connected"
+	if resp != nil {
+		text += fmt.Sprintf(" (%s, %dms)", resp.Status, resp.ResponseTime.Milliseconds())
+	}
+	if subprotocol != "" {
+		text += " - subprotocol " + subprotocol
+	}
+	w.statusLabel.SetText(text)
+	w.statusLabel.Importance = widget.SuccessImportance
+	w.statusLabel.Refresh()
+	w.SetConnected(true)
+}
+
+// ShowDisconnected displays that the connection has ended
+func (w *WebSocketPanel) ShowDisconnected(err error) {
+	if err != nil {
+		w.statusLabel.SetText("Error: " + err.Error())
+		w.statusLabel.Importance = widget.DangerImportance
+	} else {
+		w.statusLabel.SetText("Status: Disconnected")
+		w.statusLabel.Importance = widget.MediumImportance
+	}
+	w.statusLabel.Refresh()
+	w.SetConnected(false)
+}
+
+// AppendMessage adds an entry to the message log
+func (w *WebSocketPanel) AppendMessage(m models.WebSocketMessage) {
+	w.messages = append(w.messages, m)
+	w.logList.Refresh()
+	w.logList.ScrollToBottom()
+}
+
+// Clear empties the message log
+func (w *WebSocketPanel) Clear() {
+	w.messages = nil
+	w.logList.Refresh()
+	w.ShowDisconnected(nil)
+}
+
+// Draft returns the message being composed
+func (w *WebSocketPanel) Draft() string {
+	return w.composer.Text
+}
+
+// SetDraft replaces the message being composed
+func (w *WebSocketPanel) SetDraft(text string) {
+	w.composer.SetText(text)
+}
+
+// showMessage shows the full content of a logged message
+func (w *WebSocketPanel) showMessage(m models.WebSocketMessage) {
+	data := m.Data
+	if m.Type == httpclient.MessageText && httpclient.IsJSON(data) {
+		data = httpclient.FormatJSON(data)
+	}
+
+	entry := widget.NewMultiLineEntry()
+	entry.SetText(data)
+	entry.Wrapping = fyne.TextWrapWord
+
+	title := fmt.Sprintf("%s %s message", m.Direction, m.Type)
+	d := dialog.NewCustom(title, "Close", container.NewStack(entry), w.app.GetWindow())
+	d.Resize(fyne.NewSize(600, 400))
+	d.Show()
+}
+
+// messageText formats a log entry as a single list line
+func messageText(m models.WebSocketMessage) string {
+	arrow := "•"
+	switch m.Direction {
+	case models.DirectionSent:
+		arrow = "→"
+	case models.DirectionReceived:
+		arrow = "←"
+	}
+	return fmt.Sprintf("%s  %s [%s]  %s", m.Timestamp.Format("15:04:05.000"), arrow, m.Type, m.Data)
+}
+
+// parseCloseCode validates a close code entered by the user
+func parseCloseCode(s string) (int, error) {
+	code, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("close code must be a number")
+	}
+	// Codes 1004-1006 and 1012+ are reserved and must not be sent
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011, code >= 3000 && code <= 4999:
+		return code, nil
+	default:
+		return 0, fmt.Errorf("close code %d may not be sent by a client", code)
+	}
+}
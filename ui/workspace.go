@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"percentman/storage"
+)
+
+// openWorkspace is the workspace choice that asks for another directory
+const openWorkspace = "Open Folder..."
+
+// buildWorkspaceSelect creates the workspace switcher
+func (a *App) buildWorkspaceSelect() *widget.Select {
+	a.workspaceSelect = widget.NewSelect(nil, func(label string) {
+		if label == openWorkspace {
+			a.showOpenWorkspaceDialog()
+			return
+		}
+		for _, dir := range a.workspaceDirs() {
+			if workspaceLabel(dir) == label && dir != a.storage.Dir() {
+				if err := a.SwitchWorkspace(dir); err != nil {
+					dialog.ShowError(err, a.window)
+					a.refreshWorkspaceSelect()
+				}
+				return
+			}
+		}
+	})
+	a.refreshWorkspaceSelect()
+	return a.workspaceSelect
+}
+
+// workspaceDirs returns the known workspaces, falling back to the open one
+func (a *App) workspaceDirs() []string {
+	dirs, err := storage.Workspaces()
+	if err != nil {
+		return []string{a.storage.Dir()}
+	}
+	return dirs
+}
+
+// refreshWorkspaceSelect lists the known workspaces and selects the open one
+func (a *App) refreshWorkspaceSelect() {
+	var options []string
+	for _, dir := range a.workspaceDirs() {
+		options = append(options, workspaceLabel(dir))
+	}
+	a.workspaceSelect.Options = append(options, openWorkspace)
+	a.workspaceSelect.SetSelected(workspaceLabel(a.storage.Dir()))
+}
+
+// showOpenWorkspaceDialog asks for a directory and switches to it
+func (a *App) showOpenWorkspaceDialog() {
+	dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+		if err == nil && dir != nil {
+			err = a.SwitchWorkspace(dir.Path())
+		}
+		if err != nil {
+			dialog.ShowError(err, a.window)
+		}
+		a.refreshWorkspaceSelect()
+	}, a.window)
+}
+
+// SwitchWorkspace opens the data directory dir and shows its templates,
+// history and settings. The open requests are kept.
+func (a *App) SwitchWorkspace(dir string) error {
+	store, err := storage.Open(dir, "")
+	if err != nil {
+		return err
+	}
+	vault, err := storage.OpenVault(store.Dir())
+	if err != nil {
+		store.Close()
+		return err
+	}
+
+	// Running requests would otherwise record history in the new workspace
+	a.stopSessions()
+	a.SaveSession()
+
+	if a.stopWatch != nil {
+		a.stopWatch()
+		a.stopWatch = nil
+	}
+	a.storage.Close()
+	a.storage = store
+	a.vault = vault
+	// The open requests are kept, but the templates they came from are not here
+	for _, tab := range a.tabs {
+		tab.template = nil
+		a.refreshTab(tab)
+	}
+	a.watchStorage()
+	a.applyStoredSettings()
+	a.request.RefreshDefaults()
+	a.sidebar.RefreshTemplates()
+	a.sidebar.RefreshHistory()
+	a.refreshWorkspaceSelect()
+	a.showLoadErrors()
+	return nil
+}
+
+// workspaceLabel names a workspace by its directory, shortening the home directory to ~
+func workspaceLabel(dir string) string {
+	if defaultDir, err := storage.DefaultDir(); err == nil && dir == defaultDir {
+		return "Default"
+	}
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(dir, home+string(filepath.Separator)) {
+		return "~" + dir[len(home):]
+	}
+	return dir
+}